@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Time given to abort the active multipart uploads when the run is interrupted
+const interruptAbortTimeout = 10 * time.Second
+
+// MultipartUpload is an incomplete multipart upload of an entity file
+type MultipartUpload struct {
+	UploadId     string     `json:"uploadId"`
+	FileId       *uuid.UUID `json:"fileId,omitempty"`
+	Type         string     `json:"type,omitempty"`
+	OriginalPath string     `json:"originalPath,omitempty"`
+	InitiatedAt  *time.Time `json:"initiatedAt,omitempty"`
+}
+
+type MultipartUploadsContainer struct {
+	Data []MultipartUpload `json:"data"`
+}
+
+// activeMultipartUploads are the multipart uploads started by the run and not completed yet, by upload id
+var (
+	activeMultipartMu      sync.Mutex
+	activeMultipartUploads = map[string]uuid.UUID{}
+)
+
+// trackMultipartUpload remembers the started multipart upload to abort it if the run is interrupted
+func trackMultipartUpload(entityId uuid.UUID, uploadId string) {
+	activeMultipartMu.Lock()
+	defer activeMultipartMu.Unlock()
+	activeMultipartUploads[uploadId] = entityId
+}
+
+// untrackMultipartUpload forgets the completed multipart upload
+func untrackMultipartUpload(uploadId string) {
+	activeMultipartMu.Lock()
+	defer activeMultipartMu.Unlock()
+	delete(activeMultipartUploads, uploadId)
+}
+
+// getEntityMultipartUploads fetches the incomplete multipart uploads of the entity files
+func getEntityMultipartUploads(entityId uuid.UUID) ([]MultipartUpload, error) {
+	reqUrl := fmt.Sprintf("%s/entities/%s/files/uploads", apiUrl, entityId.String())
+
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+	// Process the HTTP request
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, newApiError("failed to get multipart uploads", resp.StatusCode, body)
+	}
+
+	var container MultipartUploadsContainer
+	err = json.Unmarshal(body, &container)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse multipart uploads json: %w", err)
+	}
+
+	return container.Data, nil
+}
+
+// abortMultipartUpload asks the api to abort the multipart upload and release its stored parts, an upload the api no longer knows is considered aborted
+func abortMultipartUpload(ctx context.Context, entityId uuid.UUID, uploadId string) error {
+	reqUrl := fmt.Sprintf("%s/entities/%s/files/uploads/%s", apiUrl, entityId.String(), uploadId)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", reqUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+	// Process the HTTP request
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		logger.Infof("multipart upload %s not found, already completed or aborted", uploadId)
+		return nil
+	}
+
+	if resp.StatusCode >= 400 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read the response body: %w", err)
+		}
+		return newApiError("failed to abort a multipart upload", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// abortMultipartUploads aborts the incomplete multipart uploads of the entity, or only the one with the upload id, after the confirmation, only lists them in the dry run
+func abortMultipartUploads(entityId uuid.UUID, uploadId string, dryRun bool, yes bool) error {
+	var uploads []MultipartUpload
+	if uploadId != "" {
+		uploads = []MultipartUpload{{UploadId: uploadId}}
+	} else {
+		var err error
+		uploads, err = getEntityMultipartUploads(entityId)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(uploads) == 0 {
+		logger.Infof("no incomplete multipart uploads")
+		return nil
+	}
+
+	for _, upload := range uploads {
+		if upload.InitiatedAt != nil {
+			logger.Infof("multipart upload %s (%s) %s, initiated at %s", upload.UploadId, upload.Type, upload.OriginalPath, upload.InitiatedAt.Format(time.RFC3339))
+		} else {
+			logger.Infof("multipart upload %s (%s) %s", upload.UploadId, upload.Type, upload.OriginalPath)
+		}
+	}
+
+	if dryRun {
+		logger.Infof("dry run, %d multipart uploads would be aborted", len(uploads))
+		return nil
+	}
+
+	if !yes {
+		ok, err := confirm(fmt.Sprintf("abort %d multipart uploads?", len(uploads)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("abort cancelled")
+		}
+	}
+
+	for _, upload := range uploads {
+		err := abortMultipartUpload(context.Background(), entityId, upload.UploadId)
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("aborted multipart upload %s", upload.UploadId)
+		summary.addAbortedUpload(upload.UploadId)
+	}
+
+	return nil
+}
+
+// abortActiveMultipartUploads makes a best effort to abort the multipart uploads started by the interrupted run within a short timeout,
+// the uploads left behind can be aborted later with the abortMultipart task
+func abortActiveMultipartUploads() {
+	activeMultipartMu.Lock()
+	uploads := make(map[string]uuid.UUID, len(activeMultipartUploads))
+	for uploadId, entityId := range activeMultipartUploads {
+		uploads[uploadId] = entityId
+	}
+	activeMultipartMu.Unlock()
+
+	if len(uploads) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), interruptAbortTimeout)
+	defer cancel()
+
+	for uploadId, entityId := range uploads {
+		err := abortMultipartUpload(ctx, entityId, uploadId)
+		if err != nil {
+			logger.Warningf("failed to abort multipart upload %s, run -task %s to clean it up: %v", uploadId, taskAbortMultipart, err)
+			continue
+		}
+
+		logger.Infof("aborted multipart upload %s", uploadId)
+		untrackMultipartUpload(uploadId)
+	}
+}
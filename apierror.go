@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// ApiError is returned when the API responds with an error status code, the status and message are parsed from the response json envelope
+type ApiError struct {
+	Operation  string `json:"-"`
+	StatusCode int    `json:"-"`
+	Body       string `json:"-"`
+	Status     string `json:"status,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+func (e *ApiError) Error() string {
+	if e.Status != "" || e.Message != "" {
+		return fmt.Sprintf("%s, status code: %d, status: %s, message: %s", e.Operation, e.StatusCode, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s, status code: %d, content: %s", e.Operation, e.StatusCode, e.Body)
+}
+
+// newApiError creates an error for the failed operation, the body is kept as is if it is not a json envelope
+func newApiError(operation string, statusCode int, body []byte) *ApiError {
+	e := &ApiError{
+		Operation:  operation,
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+
+	if err := json.Unmarshal(body, e); err != nil {
+		logger.Debugf("failed to parse the error response json: %v", err)
+	}
+
+	return e
+}
+
+// isEndpointUnavailable returns true if the api does not support the requested endpoint or method
+func isEndpointUnavailable(err error) bool {
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// withErrorFields returns a log entry with the api error status code, status and message as fields if the error is an api error
+func withErrorFields(err error) *logrus.Entry {
+	var apiErr *ApiError
+	if errors.As(err, &apiErr) {
+		return logger.WithFields(logrus.Fields{
+			"statusCode": apiErr.StatusCode,
+			"status":     apiErr.Status,
+			"apiMessage": apiErr.Message,
+		})
+	}
+
+	return logger
+}
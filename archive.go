@@ -0,0 +1,252 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"context"
+	"fmt"
+	"github.com/mholt/archiver/v4"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const symlinksSkip = "skip"
+const symlinksStore = "store"
+const symlinksFollow = "follow"
+
+// archiveFilesFromDisk enumerates the files to archive, symbolic links are skipped, stored as links or followed depending on the mode
+func archiveFilesFromDisk(fileMap map[string]string, symlinkMode string) ([]archiver.File, error) {
+	files, err := archiver.FilesFromDisk(nil, fileMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []archiver.File
+	for _, file := range files {
+		if file.LinkTarget == "" {
+			result = append(result, file)
+			continue
+		}
+
+		switch symlinkMode {
+		case symlinksStore:
+			logger.Infof("storing symlink '%s' -> '%s'", file.NameInArchive, file.LinkTarget)
+			result = append(result, file)
+		case symlinksFollow:
+			logger.Infof("following symlink '%s' -> '%s'", file.NameInArchive, file.LinkTarget)
+		default:
+			logger.Warningf("skipping symlink '%s' -> '%s'", file.NameInArchive, file.LinkTarget)
+		}
+	}
+
+	// Enumerate again dereferencing the links, the first pass is only used to report them
+	if symlinkMode == symlinksFollow {
+		return archiver.FilesFromDisk(&archiver.FromDiskOptions{FollowSymlinks: true}, fileMap)
+	}
+
+	return result, nil
+}
+
+const archiveProgressInterval = 5 * time.Second
+
+// archiveProgress counts the archived files and content bytes, reporting the progress periodically
+type archiveProgress struct {
+	files    int
+	stored   int // files stored without compression, e.g. already compressed
+	bytes    int64
+	total    int64
+	reported time.Time
+}
+
+func (p *archiveProgress) Write(b []byte) (int, error) {
+	p.bytes += int64(len(b))
+	if time.Since(p.reported) >= archiveProgressInterval {
+		p.report()
+	}
+	return len(b), nil
+}
+
+func (p *archiveProgress) report() {
+	p.reported = time.Now()
+	if p.total > 0 {
+		reportProgress(progressArchive, p.bytes, p.total)
+	}
+}
+
+// Modification time of the reproducible archive entries, the earliest time the zip format can represent
+var reproducibleModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// sortArchiveFiles sorts the files by the entry name, so the archive entries and the manifest do not depend on the file system enumeration order
+func sortArchiveFiles(files []archiver.File) {
+	sort.SliceStable(files, func(i, j int) bool {
+		return archiveEntryName(files[i].NameInArchive) < archiveEntryName(files[j].NameInArchive)
+	})
+}
+
+// normalizeEntryHeader drops the modification time and the permissions that differ between the machines from the entry header, executable files stay executable
+func normalizeEntryHeader(hdr *zip.FileHeader, mode fs.FileMode) {
+	hdr.Modified = reproducibleModTime
+	switch {
+	case mode.IsDir():
+		hdr.SetMode(fs.ModeDir | 0755)
+	case mode&fs.ModeSymlink != 0:
+		hdr.SetMode(fs.ModeSymlink | 0777)
+	case mode&0111 != 0:
+		hdr.SetMode(0755)
+	default:
+		hdr.SetMode(0644)
+	}
+}
+
+// archiveZip writes the files to a zip archive using the deflate compression level, level 0 stores the files without compression.
+// The reproducible archives have the entry timestamps and permissions normalized, the files are expected to be sorted.
+// The manifest (if any) is written as the last entry, the progress (if any) is reported while the content is written.
+func archiveZip(ctx context.Context, output io.Writer, files []archiver.File, level int, reproducible bool, manifest []byte, progress *archiveProgress) (err error) {
+	defer func() {
+		err = withCategory(ErrArchive, err)
+	}()
+
+	zw := zip.NewWriter(output)
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, level)
+	})
+
+	for i, file := range files {
+		// Honor the context cancellation
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hdr, err := zip.FileInfoHeader(file)
+		if err != nil {
+			return fmt.Errorf("failed to get info for file %d: %s: %w", i, file.Name(), err)
+		}
+		// Use the complete path as FileInfoHeader only sets the base name
+		hdr.Name = archiveEntryName(file.NameInArchive)
+		if reproducible {
+			normalizeEntryHeader(hdr, file.Mode())
+		}
+
+		if file.IsDir() {
+			if !strings.HasSuffix(hdr.Name, "/") {
+				hdr.Name += "/"
+			}
+			hdr.Method = zip.Store
+		} else if file.LinkTarget != "" || level == 0 || isCompressedFile(file.NameInArchive) {
+			hdr.Method = zip.Store
+		} else {
+			hdr.Method = zip.Deflate
+		}
+
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("failed to create header for file %d: %s: %w", i, file.Name(), err)
+		}
+
+		// Directories have no content
+		if file.IsDir() {
+			continue
+		}
+
+		// Symbolic links store the link target as the content
+		if file.LinkTarget != "" {
+			if _, err = w.Write([]byte(file.LinkTarget)); err != nil {
+				return fmt.Errorf("failed to write link %d: %s: %w", i, file.Name(), err)
+			}
+			continue
+		}
+
+		if progress != nil {
+			w = io.MultiWriter(w, progress)
+		}
+
+		err = copyArchiveFile(file, w)
+		if err != nil {
+			return fmt.Errorf("failed to write file %d: %s: %w", i, file.Name(), err)
+		}
+
+		if progress != nil {
+			progress.files++
+			if hdr.Method == zip.Store {
+				progress.stored++
+			}
+		}
+	}
+
+	if progress != nil {
+		progress.report()
+	}
+
+	if manifest != nil {
+		modified := time.Now()
+		if reproducible {
+			modified = reproducibleModTime
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: archiveManifestName, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return fmt.Errorf("failed to create header for the manifest: %w", err)
+		}
+		if _, err = w.Write(manifest); err != nil {
+			return fmt.Errorf("failed to write the manifest: %w", err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// copyArchiveFile copies the file content to the archive entry writer
+func copyArchiveFile(file archiver.File, w io.Writer) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+// archiveContentSize returns the total uncompressed size of the regular files
+func archiveContentSize(files []archiver.File) int64 {
+	var size int64
+	for _, file := range files {
+		if file.Mode().IsRegular() {
+			size += file.Size()
+		}
+	}
+	return size
+}
+
+// archiveFileCount returns the number of regular files to archive, directories and symlinks are not counted
+func archiveFileCount(files []archiver.File) int {
+	var count int
+	for _, file := range files {
+		if file.Mode().IsRegular() {
+			count++
+		}
+	}
+	return count
+}
+
+// Extensions of the formats compressed on their own, deflating them again wastes time without reducing the size
+var compressedFileExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".ktx2": true,
+	".mp3": true, ".ogg": true, ".opus": true, ".m4a": true, ".aac": true, ".flac": true,
+	".mp4": true, ".webm": true, ".mov": true, ".bk2": true,
+	".zip": true, ".gz": true, ".7z": true, ".rar": true, ".bz2": true, ".xz": true, ".zst": true,
+	".pak": true, ".ucas": true,
+}
+
+// isCompressedFile reports whether the file is already compressed judging by its extension
+func isCompressedFile(name string) bool {
+	return compressedFileExtensions[strings.ToLower(path.Ext(name))]
+}
+
+// archiveEntryName returns the archive entry name with forward slashes as required by the zip format, names built on Windows may have backslashes
+func archiveEntryName(name string) string {
+	return strings.ReplaceAll(name, `\`, "/")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveTree archives the dir as the reproducible archive and returns the archive hash
+func archiveTree(t *testing.T, dir string) [sha256.Size]byte {
+	files, err := archiveFilesFromDisk(map[string]string{dir: "Content"}, symlinksSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortArchiveFiles(files)
+
+	var buf bytes.Buffer
+	if err = archiveZip(context.Background(), &buf, files, 6, true, []byte(`{"files":[]}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	return sha256.Sum256(buf.Bytes())
+}
+
+func TestArchiveZipReproducible(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Maps/Level.umap":    "level",
+		"Textures/A.uasset":  "texture",
+		"Textures/B.pak":     "packed",
+		"Scripts/run.sh":     "#!/bin/sh",
+		"Empty/Nothing.uexp": "",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := archiveTree(t, dir)
+
+	// The modification times and the permissions of the non-executable files differ between the machines
+	modified := time.Now().Add(-48 * time.Hour)
+	for name := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.Chtimes(p, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if second := archiveTree(t, dir); second != first {
+		t.Errorf("reproducible archives of the same tree differ: %x != %x", first, second)
+	}
+}
+
+func TestArchiveEntryNameRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "Maps"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Maps", "Level.umap"), []byte("level"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := archiveFilesFromDisk(map[string]string{dir: "Content"}, symlinksSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The names built on Windows have backslashes
+	for i := range files {
+		files[i].NameInArchive = strings.ReplaceAll(files[i].NameInArchive, "/", `\`)
+	}
+	sortArchiveFiles(files)
+
+	var buf bytes.Buffer
+	if err = archiveZip(context.Background(), &buf, files, 6, true, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if got, want := strings.Join(names, ","), "Content/,Content/Maps/,Content/Maps/Level.umap"; got != want {
+		t.Errorf("archive entries %s, want %s", got, want)
+	}
+
+	// The entries are extracted to the same paths whatever separator the archive was created with
+	root := t.TempDir()
+	want := filepath.Join(root, "Content", "Maps", "Level.umap")
+	for _, name := range []string{"Content/Maps/Level.umap", `Content\Maps\Level.umap`} {
+		dest, err := extractPath(root, name)
+		if err != nil || dest != want {
+			t.Errorf("entry %s extracted to %s (%v), want %s", name, dest, err, want)
+		}
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		dest, err := extractPath(root, f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if err = os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(dest, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, err := os.ReadFile(want); err != nil || string(b) != "level" {
+		t.Errorf("extracted %q (%v), want level", b, err)
+	}
+
+	if _, err := extractPath(root, `..\..\evil.sh`); err == nil {
+		t.Errorf("entry escaping the root with backslashes was not rejected")
+	}
+}
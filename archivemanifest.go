@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mholt/archiver/v4"
+	"io"
+	"os"
+)
+
+// archiveManifestName is the name of the integrity manifest entry embedded into the content archive
+const archiveManifestName = "manifest.json"
+
+type ArchiveManifestFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	Hash string `json:"hash"` // hex encoded SHA-256 of the file content
+}
+
+type ArchiveManifest struct {
+	Files []ArchiveManifestFile `json:"files"`
+}
+
+// newArchiveManifest hashes the regular files to archive read from the disk paths keyed by the name in the archive and returns the serialized manifest
+func newArchiveManifest(files []archiver.File, paths map[string]string) ([]byte, error) {
+	m := ArchiveManifest{Files: []ArchiveManifestFile{}}
+	var diskPaths []string
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		if file.NameInArchive == archiveManifestName {
+			return nil, fmt.Errorf("content file '%s' conflicts with the archive manifest", file.NameInArchive)
+		}
+
+		path, ok := paths[file.NameInArchive]
+		if !ok {
+			return nil, fmt.Errorf("no disk path of file %s", file.NameInArchive)
+		}
+
+		m.Files = append(m.Files, ArchiveManifestFile{Path: file.NameInArchive, Size: file.Size()})
+		diskPaths = append(diskPaths, path)
+	}
+
+	// The files are hashed in parallel, the manifest keeps the order of the archive entries
+	hashes, err := hashFiles(diskPaths)
+	if err != nil {
+		return nil, err
+	}
+	for i := range m.Files {
+		m.Files[i].Hash = hashes[i]
+	}
+
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// writeContentManifest writes the manifest of the plugin content files to the path or stdout if the path is empty, without archiving or uploading the content
+func writeContentManifest(pluginDir string, pluginContentTempDir string, path string) error {
+	files, paths, err := collectContentFiles(pluginDir, pluginContentTempDir)
+	if err != nil {
+		return err
+	}
+
+	b, err := newArchiveManifest(files, paths)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		_, err = fmt.Fprintln(os.Stdout, string(b))
+		if err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		return nil
+	}
+
+	err = os.WriteFile(path, b, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	logger.Infof("written the content manifest to %s", path)
+	return nil
+}
+
+// readArchiveManifest parses the manifest archive entry
+func readArchiveManifest(r io.Reader) (ArchiveManifest, error) {
+	var m ArchiveManifest
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return m, fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return m, fmt.Errorf("failed to parse archive manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// filterArchiveManifest returns the manifest of the files matching the include patterns only
+func filterArchiveManifest(m ArchiveManifest, patterns []string) ArchiveManifest {
+	filtered := ArchiveManifest{Files: []ArchiveManifestFile{}}
+	for _, file := range m.Files {
+		if matchesIncludePatterns(file.Path, patterns) {
+			filtered.Files = append(filtered.Files, file)
+		}
+	}
+	return filtered
+}
+
+// validateExtractedFiles checks the size and hash of every file listed in the archive manifest extracted to the dir, returns the number of invalid files
+func validateExtractedFiles(m ArchiveManifest, dir string) int {
+	var invalid int
+	for _, expected := range m.Files {
+		path, err := extractPath(dir, expected.Path)
+		if err != nil {
+			logger.Errorf("invalid manifest entry: %v", err)
+			invalid++
+			continue
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			logger.Errorf("missing extracted file '%s': %v", expected.Path, err)
+			invalid++
+			continue
+		}
+
+		if fi.Size() != expected.Size {
+			logger.Errorf("extracted file '%s' size mismatch, expected: %d, actual: %d", expected.Path, expected.Size, fi.Size())
+			invalid++
+			continue
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			logger.Errorf("failed to hash extracted file '%s': %v", expected.Path, err)
+			invalid++
+			continue
+		}
+
+		if hash != expected.Hash {
+			logger.Errorf("extracted file '%s' hash mismatch, expected: %s, actual: %s", expected.Path, expected.Hash, hash)
+			invalid++
+		}
+	}
+
+	return invalid
+}
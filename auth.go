@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultClientId = "veverse-sdk-automation"
+
+// OAuth 2.0 device authorization grant type (RFC 8628)
+const grantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+const grantTypeRefreshToken = "refresh_token"
+
+// The access token is refreshed if it expires sooner than this
+const tokenRefreshMargin = 1 * time.Minute
+
+// loginCache is the token acquired with the login task used by the run, nil if the token is passed with -token.
+// tokenMu guards the cache and the api token refreshed by the concurrent requests.
+var (
+	tokenMu    sync.Mutex
+	loginCache *TokenCache
+)
+
+// TokenCache is the token acquired with the login task, kept in the user config dir
+type TokenCache struct {
+	AuthUrl      string    `json:"authUrl"`
+	ClientId     string    `json:"clientId"`
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt,omitempty"`
+}
+
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationUri         string `json:"verification_uri"`
+	VerificationUriComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+// OAuthError is the error response of the auth server token endpoint
+type OAuthError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("auth error, status code: %d, error: %s, description: %s", e.StatusCode, e.Code, e.Description)
+	}
+	return fmt.Sprintf("auth error, status code: %d, error: %s", e.StatusCode, e.Code)
+}
+
+// tokenCachePath returns the path of the cached token in the user config dir
+func tokenCachePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the user config dir: %w", err)
+	}
+	return filepath.Join(dir, "veverse", "token.json"), nil
+}
+
+// readTokenCache reads the cached token, returns nil if there is none
+func readTokenCache() (*TokenCache, error) {
+	path, err := tokenCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read the token cache: %w", err)
+	}
+
+	var cache TokenCache
+	err = json.Unmarshal(b, &cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the token cache: %w", err)
+	}
+
+	return &cache, nil
+}
+
+// writeTokenCache writes the token readable by the current user only
+func writeTokenCache(cache TokenCache) error {
+	path, err := tokenCachePath()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create the token cache dir: %w", err)
+	}
+
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize the token cache: %w", err)
+	}
+
+	err = os.WriteFile(path, b, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write the token cache: %w", err)
+	}
+
+	return nil
+}
+
+// postAuthForm sends the form to the auth server endpoint and parses the json response into the value
+func postAuthForm(endpoint string, form url.Values, v interface{}) error {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		e := &OAuthError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(body, e); err != nil || e.Code == "" {
+			return newApiError("failed to request "+endpoint, resp.StatusCode, body)
+		}
+		return e
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse the response json: %w", err)
+	}
+
+	return nil
+}
+
+// newTokenCache returns the cache of the token response, the refresh token is kept if the response has no new one
+func newTokenCache(authUrl string, clientId string, resp TokenResponse, refreshToken string) TokenCache {
+	cache := TokenCache{
+		AuthUrl:      authUrl,
+		ClientId:     clientId,
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+	}
+	if cache.RefreshToken == "" {
+		cache.RefreshToken = refreshToken
+	}
+	if resp.ExpiresIn > 0 {
+		cache.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return cache
+}
+
+// login acquires a token with the device authorization flow: the user confirms the code in the browser while the token endpoint is polled
+func login(authUrl string, clientId string) error {
+	authUrl = strings.TrimSuffix(authUrl, "/")
+
+	var code DeviceCode
+	err := postAuthForm(authUrl+"/device/code", url.Values{"client_id": {clientId}}, &code)
+	if err != nil {
+		return fmt.Errorf("failed to request the device code: %w", err)
+	}
+
+	if code.VerificationUriComplete != "" {
+		fmt.Fprintf(os.Stderr, "open %s in the browser and confirm the code %s\n", code.VerificationUriComplete, code.UserCode)
+	} else {
+		fmt.Fprintf(os.Stderr, "open %s in the browser and enter the code %s\n", code.VerificationUri, code.UserCode)
+	}
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	form := url.Values{
+		"grant_type":  {grantTypeDeviceCode},
+		"device_code": {code.DeviceCode},
+		"client_id":   {clientId},
+	}
+	for {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the device code expired, run the login again")
+		}
+		time.Sleep(interval)
+
+		var resp TokenResponse
+		err = postAuthForm(authUrl+"/token", form, &resp)
+		if err == nil {
+			err = writeTokenCache(newTokenCache(authUrl, clientId, resp, ""))
+			if err != nil {
+				return err
+			}
+			logger.Infof("logged in")
+			return nil
+		}
+
+		var oauthErr *OAuthError
+		if !errors.As(err, &oauthErr) {
+			return err
+		}
+
+		switch oauthErr.Code {
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		default:
+			return fmt.Errorf("failed to get the token: %w", err)
+		}
+	}
+}
+
+// refreshAccessToken exchanges the refresh token for a new access token and updates the cache
+func refreshAccessToken(cache *TokenCache) error {
+	if cache.RefreshToken == "" {
+		return fmt.Errorf("the token expired and can't be refreshed, run -task login")
+	}
+
+	form := url.Values{
+		"grant_type":    {grantTypeRefreshToken},
+		"refresh_token": {cache.RefreshToken},
+		"client_id":     {cache.ClientId},
+	}
+
+	var resp TokenResponse
+	err := postAuthForm(cache.AuthUrl+"/token", form, &resp)
+	if err != nil {
+		var oauthErr *OAuthError
+		if errors.As(err, &oauthErr) && oauthErr.Code == "invalid_grant" {
+			return fmt.Errorf("the login session expired, run -task login")
+		}
+		return fmt.Errorf("failed to refresh the token: %w", err)
+	}
+
+	*cache = newTokenCache(cache.AuthUrl, cache.ClientId, resp, cache.RefreshToken)
+	return writeTokenCache(*cache)
+}
+
+// cachedToken returns the access token acquired with the login task, refreshing it if it is about to expire
+func cachedToken() (string, error) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	cache, err := readTokenCache()
+	if err != nil {
+		return "", err
+	}
+	if cache == nil || cache.AccessToken == "" {
+		return "", fmt.Errorf("no token, pass -token or run -task login")
+	}
+
+	if !cache.ExpiresAt.IsZero() && time.Until(cache.ExpiresAt) < tokenRefreshMargin {
+		logger.Infof("refreshing the access token")
+		err = refreshAccessToken(cache)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	loginCache = cache
+	return cache.AccessToken, nil
+}
+
+// currentToken returns the api token, it may be refreshed by a concurrent request
+func currentToken() string {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+	return token
+}
+
+// refreshUnauthorizedToken refreshes the rejected access token with the cached refresh token, returns false if it can't be refreshed.
+// The token refreshed by a concurrent request is returned as is.
+func refreshUnauthorizedToken(rejected string) (string, bool) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	if loginCache == nil {
+		return "", false
+	}
+	if loginCache.AccessToken != rejected {
+		return loginCache.AccessToken, true
+	}
+
+	logger.Infof("access token rejected, refreshing")
+	err := refreshAccessToken(loginCache)
+	if err != nil {
+		logger.Errorf("failed to refresh the access token: %v", err)
+		return "", false
+	}
+
+	token = loginCache.AccessToken
+	return token, true
+}
+
+// tokenRefreshTransport refreshes the access token acquired with the login task and repeats the request once if the api responds with 401
+type tokenRefreshTransport struct {
+	base http.RoundTripper
+}
+
+func (t tokenRefreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, withCategory(ErrNetwork, err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return resp, nil
+	}
+	rejected := strings.TrimPrefix(authorization, "Bearer ")
+
+	// The streamed bodies can only be repeated if they can be recreated
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	refreshed, ok := refreshUnauthorizedToken(rejected)
+	if !ok {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+	}
+	retry.Header.Set("Authorization", fmt.Sprintf("Bearer %s", refreshed))
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	resp, err = t.base.RoundTrip(retry)
+	if err != nil {
+		return nil, withCategory(ErrNetwork, err)
+	}
+	return resp, nil
+}
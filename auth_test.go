@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTokenRefreshConcurrentRequests(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"access_token":"refreshed","expires_in":3600}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer refreshed" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	token = "expired"
+	loginCache = &TokenCache{AuthUrl: server.URL, AccessToken: "expired", RefreshToken: "refresh"}
+	httpClient = newHttpClient(defaultMaxIdleConns, defaultMaxConnsPerHost, false, false, defaultMaxRedirects)
+	t.Cleanup(func() {
+		token = ""
+		loginCache = nil
+		httpClient = &http.Client{}
+	})
+
+	// The requests read the token while the first rejected one refreshes it
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest("GET", server.URL+"/entities", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status code %d, want the request repeated with the refreshed token", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := currentToken(); got != "refreshed" {
+		t.Errorf("token %q, want the refreshed token", got)
+	}
+}
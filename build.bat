@@ -1,8 +1,11 @@
 @echo off
-go build -o sdk-automation.exe -ldflags "-s -w" main.go
+for /f %%i in ('git describe --tags --always --dirty') do set VERSION=%%i
+for /f %%i in ('powershell -NoProfile -Command "Get-Date -Format o"') do set BUILD_DATE=%%i
+set LDFLAGS=-s -w -X main.version=%VERSION% -X main.buildDate=%BUILD_DATE%
+go build -o sdk-automation.exe -ldflags "%LDFLAGS%" .
 @REM set GOOS=darwin
 @REM set GOARCH=amd64
-@REM go build -o metaverse-sdk-automation-mac -ldflags "-s -w" main.go
+@REM go build -o metaverse-sdk-automation-mac -ldflags "%LDFLAGS%" .
 @REM set GOOS=darwin
 @REM set GOARCH=arm64
-@REM go build -o metaverse-sdk-automation-mac-m1 -ldflags "-s -w" main.go
\ No newline at end of file
+@REM go build -o metaverse-sdk-automation-mac-m1 -ldflags "%LDFLAGS%" .
\ No newline at end of file
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File in the plugin directory caching the content hashes between the runs
+const checksumCacheFileName = ".veverse-cache"
+
+type checksumCacheEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Hash    string    `json:"hash"`
+}
+
+// ChecksumCache keeps the file hashes keyed by the absolute path, an entry is valid while the file size and modification time are unchanged
+type ChecksumCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]checksumCacheEntry
+	dirty   bool
+}
+
+// checksumCache is used by hashFile if loaded
+var checksumCache *ChecksumCache
+
+// loadChecksumCache reads the cache file, a missing or unreadable cache is started from scratch
+func loadChecksumCache(dir string) *ChecksumCache {
+	c := &ChecksumCache{
+		path:    filepath.Join(dir, checksumCacheFileName),
+		entries: map[string]checksumCacheEntry{},
+	}
+
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warningf("failed to read checksum cache: %v", err)
+		}
+		return c
+	}
+
+	err = json.Unmarshal(b, &c.entries)
+	if err != nil {
+		logger.Warningf("failed to parse checksum cache, starting from scratch: %v", err)
+		c.entries = map[string]checksumCacheEntry{}
+	}
+
+	return c
+}
+
+// hash returns the cached hash of the file if the file is unchanged, hashes and caches the file otherwise
+func (c *ChecksumCache) hash(path string) (string, error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.Size == fi.Size() && entry.ModTime.Equal(fi.ModTime()) {
+		return entry.Hash, nil
+	}
+
+	hash, err := computeFileHash(path)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = checksumCacheEntry{Size: fi.Size(), ModTime: fi.ModTime(), Hash: hash}
+	c.dirty = true
+	c.mu.Unlock()
+
+	return hash, nil
+}
+
+// save drops the entries of the removed files and writes the cache file if anything changed
+func (c *ChecksumCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if _, err := os.Stat(key); os.IsNotExist(err) {
+			delete(c.entries, key)
+			c.dirty = true
+		}
+	}
+
+	if !c.dirty {
+		return nil
+	}
+
+	b, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to serialize checksum cache: %w", err)
+	}
+
+	err = os.WriteFile(c.path, b, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write checksum cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// useChecksumCache loads the cache of the dir for hashFile, the returned func saves and unloads it
+func useChecksumCache(dir string) func() {
+	checksumCache = loadChecksumCache(dir)
+	return func() {
+		if checksumCache == nil {
+			return
+		}
+		if err := checksumCache.save(); err != nil {
+			logger.Warningf("%v", err)
+		}
+		checksumCache = nil
+	}
+}
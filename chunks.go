@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Number of parts a multipart upload is split into when the chunk size is picked automatically
+const targetPartCount = 1000
+
+// S3 limits the multipart upload part size to 5 GiB
+const maxPartSize = 5 * 1024 * 1024 * 1024
+
+// Single request uploads only use the chunk size for the read buffer, larger buffers do not make the upload faster
+const maxReadBufferSize = 16 * 1024 * 1024
+
+// autoChunkSize picks the chunk size for the file size.
+// Multipart uploads send a part per chunk, so the file is split into about targetPartCount parts between minChunkSize and maxPartSize.
+// Single request uploads stream the whole file, so the chunk is only the read buffer and is kept between minChunkSize and maxReadBufferSize.
+func autoChunkSize(size int64, multipart bool) int64 {
+	chunk := (size + targetPartCount - 1) / targetPartCount
+
+	// Round up to a whole MiB
+	chunk = (chunk + minChunkSize - 1) / minChunkSize * minChunkSize
+
+	limit := int64(maxReadBufferSize)
+	if multipart {
+		limit = maxPartSize
+	}
+
+	if chunk < minChunkSize {
+		return minChunkSize
+	}
+	if chunk > limit {
+		return limit
+	}
+	return chunk
+}
+
+// checkMaxSize fails if the measured size exceeds the -maxSize limit, e.g. when the content path points to the whole build output
+func checkMaxSize(what string, size int64) error {
+	if maxSize > 0 && size > maxSize {
+		return fmt.Errorf("%s size %d bytes exceeds the limit of %d bytes, check the content path or raise -maxSize", what, size, maxSize)
+	}
+	return nil
+}
+
+// checkMaxFiles fails if the number of files to archive exceeds the -maxFiles limit, e.g. when the content path points to the wrong dir, only warns with -maxFilesWarn
+func checkMaxFiles(count int) error {
+	if maxFiles <= 0 || count <= maxFiles {
+		return nil
+	}
+
+	err := fmt.Errorf("%d files to archive exceed the limit of %d files, check the content path or raise -maxFiles", count, maxFiles)
+	if maxFilesWarn {
+		logger.Warningf("%v", err)
+		return nil
+	}
+	return err
+}
+
+// Each read buffer holds a chunk in memory
+const maxReadBuffers = 64
+
+// readChunk is a buffer filled by the read ahead goroutine
+type readChunk struct {
+	buffer []byte
+	n      int
+	err    error
+}
+
+// copyChunks copies the reader to the writer by readBufferSize chunks calling onChunk after each written chunk.
+// With more than one read buffer the next chunks are read by a goroutine while the current one is written, so the disk reads overlap the network writes.
+func copyChunks(w io.Writer, r io.Reader, onChunk func(n int)) error {
+	if readBuffers <= 1 {
+		buffer := make([]byte, readBufferSize)
+		for {
+			n, err := r.Read(buffer)
+			if n > 0 {
+				if _, err := w.Write(buffer[:n]); err != nil {
+					return fmt.Errorf("failed to write file bytes: %w", err)
+				}
+				onChunk(n)
+			}
+			if err != nil {
+				if err != io.EOF {
+					return fmt.Errorf("failed to read from the file: %w", err)
+				}
+				return nil
+			}
+		}
+	}
+
+	// The buffers are passed back and forth, so at most readBuffers chunks are held in memory
+	free := make(chan []byte, readBuffers)
+	for i := 0; i < readBuffers; i++ {
+		free <- make([]byte, readBufferSize)
+	}
+	filled := make(chan readChunk, readBuffers)
+
+	// Stop the reader if the write fails
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(filled)
+		for {
+			var buffer []byte
+			select {
+			case buffer = <-free:
+			case <-done:
+				return
+			}
+
+			n, err := r.Read(buffer)
+			if n > 0 {
+				select {
+				case filled <- readChunk{buffer: buffer, n: n}:
+				case <-done:
+					return
+				}
+			} else {
+				free <- buffer
+			}
+
+			if err != nil {
+				if err != io.EOF {
+					select {
+					case filled <- readChunk{err: err}:
+					case <-done:
+					}
+				}
+				return
+			}
+		}
+	}()
+
+	for chunk := range filled {
+		if chunk.err != nil {
+			return fmt.Errorf("failed to read from the file: %w", chunk.err)
+		}
+
+		if _, err := w.Write(chunk.buffer[:chunk.n]); err != nil {
+			return fmt.Errorf("failed to write file bytes: %w", err)
+		}
+		onChunk(chunk.n)
+
+		free <- chunk.buffer
+	}
+
+	return nil
+}
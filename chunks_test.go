@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// latencyReader and latencyWriter delay every call like the disk reads and the network writes
+type latencyReader struct {
+	reader io.Reader
+	delay  time.Duration
+}
+
+func (r latencyReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	return r.reader.Read(p)
+}
+
+type latencyWriter struct {
+	delay time.Duration
+}
+
+func (w latencyWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return len(p), nil
+}
+
+func BenchmarkCopyChunks(b *testing.B) {
+	content := make([]byte, 64*minChunkSize)
+
+	savedSize, savedBuffers := readBufferSize, readBuffers
+	b.Cleanup(func() { readBufferSize, readBuffers = savedSize, savedBuffers })
+	readBufferSize = minChunkSize
+
+	for _, buffers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("readBuffers=%d", buffers), func(b *testing.B) {
+			readBuffers = buffers
+			b.SetBytes(int64(len(content)))
+			for i := 0; i < b.N; i++ {
+				r := latencyReader{reader: bytes.NewReader(content), delay: 100 * time.Microsecond}
+				if err := copyChunks(latencyWriter{delay: 100 * time.Microsecond}, r, func(int) {}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
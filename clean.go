@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// getPluginArtifacts returns the existing generated plugin files and dirs: the unzipped package zip, the temp content dir and, if requested, the extract dir created by the extraction
+func getPluginArtifacts(projectName string, pluginName string, includeExtracted bool) ([]string, error) {
+	pluginDir, err := getPluginDir(projectName, pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	pluginContentTempDir, err := getPluginTempDir(projectName, pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{
+		filepath.Join(pluginDir, "temp", pluginName+".zip"),
+		pluginContentTempDir,
+	}
+
+	if includeExtracted {
+		extractRoot, err := resolveExtractDir(pluginDir, extractDir, false)
+		if err != nil {
+			return nil, err
+		}
+
+		// Never remove the whole plugin dir or a dir the extraction did not create, e.g. the default Content dir with the source content
+		if extractRoot == filepath.Clean(pluginDir) {
+			return nil, fmt.Errorf("extract dir %s is the plugin dir", extractRoot)
+		}
+		if _, err := os.Lstat(extractRoot); err == nil && !isMarkedExtractDir(extractRoot) {
+			return nil, fmt.Errorf("extract dir %s was not created by the %s task, refusing to remove it", extractRoot, taskUnzipPackageSource)
+		}
+		candidates = append(candidates, extractRoot)
+	}
+
+	var artifacts []string
+	for _, path := range candidates {
+		if _, err := os.Lstat(path); err == nil {
+			artifacts = append(artifacts, path)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+	}
+
+	return artifacts, nil
+}
+
+// cleanPluginArtifacts removes the generated plugin files and dirs after the confirmation, only lists them in the dry run
+func cleanPluginArtifacts(projectName string, pluginName string, includeExtracted bool, dryRun bool, yes bool) error {
+	artifacts, err := getPluginArtifacts(projectName, pluginName, includeExtracted)
+	if err != nil {
+		return err
+	}
+
+	if len(artifacts) == 0 {
+		logger.Infof("nothing to clean")
+		return nil
+	}
+
+	for _, path := range artifacts {
+		logger.Infof("generated artifact: %s", path)
+	}
+
+	if dryRun {
+		logger.Infof("dry run, %d artifacts would be removed", len(artifacts))
+		return nil
+	}
+
+	if !yes {
+		ok, err := confirm(fmt.Sprintf("remove %d artifacts?", len(artifacts)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("cleanup cancelled")
+		}
+	}
+
+	for _, path := range artifacts {
+		err = os.RemoveAll(path)
+		if err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+
+		logger.Infof("removed %s", path)
+		summary.addRemoved(path)
+	}
+
+	return nil
+}
+
+// cleanTempContentDir removes the plugin content temp dir produced by the cook step once the content is uploaded, only logs it in the dry run
+func cleanTempContentDir(dir string, dryRun bool) error {
+	if _, err := os.Lstat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to stat %s: %w", dir, err)
+	}
+
+	if dryRun {
+		logger.Infof("dry run, the temp content dir %s would be removed", dir)
+		return nil
+	}
+
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+
+	logger.Infof("removed the temp content dir %s", dir)
+	summary.addRemoved(dir)
+	return nil
+}
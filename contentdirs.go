@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mholt/archiver/v4"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File in the plugin directory listing the content directories excluded from the package, one per line relative to the content dir
+const pluginIgnoreFileName = ".upluginignore"
+
+// readPluginIgnore returns the content directories excluded by the plugin ignore file, empty if there is no such file
+func readPluginIgnore(pluginDir string) ([]string, error) {
+	file, err := os.Open(filepath.Join(pluginDir, pluginIgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open plugin ignore file: %w", err)
+	}
+	defer file.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dirs = append(dirs, line)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read plugin ignore file: %w", err)
+	}
+
+	return dirs, nil
+}
+
+// normalizeContentDir converts the directory to the slash separated form used for the names in the archive
+func normalizeContentDir(dir string) string {
+	return strings.Trim(path.Clean(filepath.ToSlash(dir)), "/")
+}
+
+// isInContentDir reports whether the name in the archive is the directory or is inside of it
+func isInContentDir(name string, dir string) bool {
+	name = strings.TrimSuffix(name, "/")
+	return name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// filterContentDirs removes the archive entries outside of the included directories (if any) and inside of the excluded ones,
+// the parent directories of the included ones are kept
+func filterContentDirs(files []archiver.File, includeDirs []string, excludeDirs []string) []archiver.File {
+	if len(includeDirs) == 0 && len(excludeDirs) == 0 {
+		return files
+	}
+
+	var include, exclude []string
+	for _, dir := range includeDirs {
+		include = append(include, normalizeContentDir(dir))
+	}
+	for _, dir := range excludeDirs {
+		exclude = append(exclude, normalizeContentDir(dir))
+	}
+
+	var result []archiver.File
+	for _, file := range files {
+		excluded := false
+		for _, dir := range exclude {
+			if isInContentDir(file.NameInArchive, dir) {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+
+		included := len(include) == 0
+		for _, dir := range include {
+			if isInContentDir(file.NameInArchive, dir) || (file.IsDir() && isInContentDir(dir, strings.TrimSuffix(file.NameInArchive, "/"))) {
+				included = true
+				break
+			}
+		}
+		if included {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// contentTopDirs returns the sorted distinct top level entries of the archive
+func contentTopDirs(files []archiver.File) []string {
+	set := map[string]bool{}
+	for _, file := range files {
+		top, _, _ := strings.Cut(strings.TrimSuffix(file.NameInArchive, "/"), "/")
+		set[top] = true
+	}
+
+	var dirs []string
+	for dir := range set {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
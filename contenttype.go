@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gabriel-vasile/mimetype"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Content type of the unrecognized files
+const defaultContentType = "application/octet-stream"
+
+// mimeOverrides maps the lowercase file extensions including the dot to the content types forced by the -mimeMap file
+var mimeOverrides map[string]string
+
+// readMimeMap reads the content type overrides, one "extension mime" or "extension=mime" pair per line, e.g. ".uasset application/octet-stream"
+func readMimeMap(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mime map: %w", err)
+	}
+	defer file.Close()
+
+	overrides := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(strings.Replace(line, "=", " ", 1))
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid mime map line %d: %q, expected an extension and a mime", n, line)
+		}
+
+		ext := strings.ToLower(fields[0])
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if _, _, err := mime.ParseMediaType(fields[1]); err != nil {
+			return nil, fmt.Errorf("invalid mime map line %d: %q: %w", n, line, err)
+		}
+		overrides[ext] = fields[1]
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mime map: %w", err)
+	}
+
+	return overrides, nil
+}
+
+// overrideContentType returns the content type forced by the mime map for the file extension, if any
+func overrideContentType(path string) (string, bool) {
+	contentType, ok := mimeOverrides[strings.ToLower(filepath.Ext(path))]
+	if ok {
+		logger.Infof("using the mapped content type %s for %s", contentType, filepath.Base(path))
+	}
+	return contentType, ok
+}
+
+// detectContentType detects the content type of the file read from the reader.
+// Empty, unreadable or unrecognized content falls back to the type of the file extension and then to the fallback type.
+func detectContentType(r io.Reader, path string, size int64, fallback string) string {
+	detected, err := mimetype.DetectReader(r)
+	if err == nil && size > 0 && !detected.Is(defaultContentType) {
+		return detected.String()
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = fallback
+	}
+
+	if err != nil {
+		logger.Warningf("failed to detect the %s content type, using %s: %v", filepath.Base(path), contentType, err)
+	} else {
+		logger.Warningf("the %s content type is inconclusive, using %s", filepath.Base(path), contentType)
+	}
+	return contentType
+}
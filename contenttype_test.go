@@ -0,0 +1,49 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDetectContentTypeEmptyAndTruncated(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, err := zw.Create("Maps/Level.umap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte(strings.Repeat("level", 100))); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	truncated := archive.Bytes()[:archive.Len()/2]
+
+	tests := []struct {
+		name string
+		r    io.Reader
+		path string
+		size int64
+		want string
+	}{
+		{"empty file", bytes.NewReader(nil), "content.vvpkg", 0, "application/zip"},
+		{"empty file with known extension", bytes.NewReader(nil), "notes.json", 0, "application/json"},
+		{"truncated archive", bytes.NewReader(truncated), "content.vvpkg", int64(len(truncated)), "application/zip"},
+		{"truncated read", io.MultiReader(bytes.NewReader(truncated[:2]), iotest.ErrReader(errors.New("unexpected EOF"))), "content.vvpkg", int64(len(truncated)), "application/zip"},
+		{"unrecognized content", strings.NewReader("\x00\x01\x02\x03"), "content.uasset", 4, defaultContentType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectContentType(tt.r, tt.path, tt.size, tt.want); got != tt.want {
+				t.Errorf("content type %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type FileMetadataContainer struct {
+	Data FileMetadata `json:"data"`
+}
+
+// registerDuplicateFile asks the api for a blob with the same SHA-256 and registers it for the entity instead of uploading the file again.
+// Returns nil if the api has no such blob.
+func registerDuplicateFile(entityId uuid.UUID, fileType string, mime string, path string, originalPath string, params map[string]string) (*FileMetadata, error) {
+	hash, err := hashFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	reqUrl := fmt.Sprintf("%s/files/dedup?entityId=%s&type=%s&mime=%s&hash=%s&original-path=%s", apiUrl, entityId.String(), fileType, url.QueryEscape(mime), hash, url.QueryEscape(originalPath))
+
+	// Add query parameters if any supplied
+	for key, value := range params {
+		reqUrl += fmt.Sprintf("&%s=%s", key, url.QueryEscape(value))
+	}
+
+	req, err := http.NewRequest("POST", reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+	// Process the HTTP request
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, newApiError("failed to check for a duplicate file", resp.StatusCode, body)
+	}
+
+	// No content means the api has no blob with the hash
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	var container FileMetadataContainer
+	err = json.Unmarshal(body, &container)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file json: %w", err)
+	}
+	if container.Data.Id == nil {
+		return nil, fmt.Errorf("malformed dedup response: no file id returned")
+	}
+
+	return &container.Data, nil
+}
+
+// findDuplicateFile registers the blob the api already has for the entity, returns nil to upload the file on a miss or if the api has no dedup support
+func findDuplicateFile(entityId uuid.UUID, fileType string, mime string, path string, originalPath string, params map[string]string) *FileMetadata {
+	if !dedup {
+		return nil
+	}
+
+	metadata, err := registerDuplicateFile(entityId, fileType, mime, path, originalPath, params)
+	if err != nil {
+		if isEndpointUnavailable(err) {
+			logger.Debugf("dedup is unavailable: %v", err)
+		} else {
+			withErrorFields(err).Warningf("failed to check for a duplicate file, uploading: %v", err)
+		}
+		return nil
+	}
+
+	if metadata != nil {
+		logger.Infof("the api already has the %s content, registered file %s without uploading", originalPath, metadata.Id.String())
+	}
+	return metadata
+}
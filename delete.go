@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// deleteEntityFile deletes the entity file by id
+func deleteEntityFile(entityId uuid.UUID, fileId uuid.UUID) error {
+	reqUrl := fmt.Sprintf("%s/entities/%s/files/%s", apiUrl, entityId.String(), fileId.String())
+
+	req, err := http.NewRequest("DELETE", reqUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+	// Process the HTTP request
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read the response body: %w", err)
+		}
+		return newApiError("failed to delete a file", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// selectFilesToDelete returns the entity files matching the file id and the type, at least one of them is required
+func selectFilesToDelete(files []FileMetadata, fileId uuid.UUID, fileType string) ([]FileMetadata, error) {
+	if fileId.IsNil() && fileType == "" {
+		return nil, fmt.Errorf("no file id or type to delete")
+	}
+
+	var selected []FileMetadata
+	for _, file := range files {
+		if file.Id == nil {
+			continue
+		}
+		if !fileId.IsNil() && *file.Id != fileId {
+			continue
+		}
+		if fileType != "" && file.Type != fileType {
+			continue
+		}
+		selected = append(selected, file)
+	}
+
+	return selected, nil
+}
+
+// confirm asks the user to confirm the action on the terminal, anything but yes is a refusal
+func confirm(prompt string) (bool, error) {
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("cannot ask for confirmation without a terminal, pass -yes to confirm")
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read the answer: %w", err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// deleteEntityFiles deletes the entity files matching the file id and the type after the confirmation
+func deleteEntityFiles(entityId uuid.UUID, fileId uuid.UUID, fileType string, yes bool) error {
+	files, err := getEntityFiles(entityId)
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectFilesToDelete(files, fileId, fileType)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no files to delete")
+	}
+
+	for _, file := range selected {
+		logger.Infof("file %s (%s), platform: %s, deployment: %s, version: %d", file.Id.String(), file.Type, file.Platform, file.Deployment, file.Version)
+	}
+
+	if !yes {
+		ok, err := confirm(fmt.Sprintf("delete %d files?", len(selected)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("deletion cancelled")
+		}
+	}
+
+	for _, file := range selected {
+		err = deleteEntityFile(entityId, *file.Id)
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("deleted file %s (%s)", file.Id.String(), file.Type)
+		summary.addDeletedFile(file)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errFreeSpaceUnsupported = errors.New("free space check is not supported on this platform")
+
+// checkFreeSpace fails if the dir has less free space than the estimated archive size.
+// The archive is at most about the content size, the compression is not taken into account, so the estimate errs on the safe side.
+func checkFreeSpace(dir string, contentSize int64) error {
+	free, err := freeSpace(dir)
+	if err != nil {
+		// The check is best effort, the archive step fails anyway if the disk fills up
+		logger.Warningf("failed to get the free space of %s: %v", dir, err)
+		return nil
+	}
+
+	// Leave room for the zip headers and the central directory
+	required := uint64(contentSize) + uint64(contentSize)/100
+	if free < required {
+		return fmt.Errorf("not enough free space in %s to archive %d bytes of content, %d bytes free, %d bytes required, pass a different -archiveDir", dir, contentSize, free, required)
+	}
+
+	logger.Debugf("%d bytes free in %s, %d bytes required", free, dir, required)
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const checkOk = "OK"
+const checkWarn = "WARN"
+const checkFail = "FAIL"
+
+// checkDir reports whether the dir exists
+func checkDir(name string, dir string, err error, missingStatus string) CheckSummary {
+	if err != nil {
+		return CheckSummary{Name: name, Status: checkFail, Detail: err.Error()}
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil || !fi.IsDir() {
+		return CheckSummary{Name: name, Status: missingStatus, Detail: fmt.Sprintf("%s does not exist", dir)}
+	}
+
+	return CheckSummary{Name: name, Status: checkOk, Detail: dir}
+}
+
+// checkToken decodes the JWT claims without verifying the signature and checks the token expiration time
+func checkToken(token string) CheckSummary {
+	if token == "" {
+		return CheckSummary{Name: "token", Status: checkFail, Detail: "no token"}
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return CheckSummary{Name: "token", Status: checkFail, Detail: "malformed token, expected a JWT"}
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return CheckSummary{Name: "token", Status: checkFail, Detail: fmt.Sprintf("malformed token claims: %v", err)}
+	}
+
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	err = json.Unmarshal(b, &claims)
+	if err != nil {
+		return CheckSummary{Name: "token", Status: checkFail, Detail: fmt.Sprintf("malformed token claims: %v", err)}
+	}
+
+	if claims.ExpiresAt == 0 {
+		return CheckSummary{Name: "token", Status: checkWarn, Detail: "no expiration time"}
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if time.Now().After(expiresAt) {
+		return CheckSummary{Name: "token", Status: checkFail, Detail: fmt.Sprintf("expired at %s", expiresAt.Format(time.RFC3339))}
+	}
+
+	return CheckSummary{Name: "token", Status: checkOk, Detail: fmt.Sprintf("expires at %s", expiresAt.Format(time.RFC3339))}
+}
+
+// runDoctor checks the resolved environment without uploading anything
+func runDoctor() []CheckSummary {
+	var checks []CheckSummary
+
+	projectDir, err := getProjectDir(project)
+	checks = append(checks, checkDir("project dir", projectDir, err, checkFail))
+
+	projectFile, err := getProjectFile(project)
+	if err != nil {
+		checks = append(checks, CheckSummary{Name: "uproject", Status: checkFail, Detail: err.Error()})
+	} else {
+		checks = append(checks, CheckSummary{Name: "uproject", Status: checkOk, Detail: projectFile})
+	}
+
+	pluginDir, err := getPluginDir(project, plugin)
+	checks = append(checks, checkDir("plugin dir", pluginDir, err, checkFail))
+
+	if err == nil {
+		descriptorPath, err := getPluginDescriptorPath(pluginDir, plugin)
+		if err != nil {
+			checks = append(checks, CheckSummary{Name: "uplugin", Status: checkFail, Detail: err.Error()})
+		} else {
+			checks = append(checks, CheckSummary{Name: "uplugin", Status: checkOk, Detail: descriptorPath})
+		}
+	}
+
+	// The content temp dir is created by the packagePlugin task
+	pluginContentTempDir, err := getPluginTempDir(project, plugin)
+	checks = append(checks, checkDir("content temp dir", pluginContentTempDir, err, checkWarn))
+
+	projectVersion, err := getProjectVersion(project)
+	if err != nil {
+		checks = append(checks, CheckSummary{Name: "project version", Status: checkFail, Detail: err.Error()})
+	} else {
+		checks = append(checks, CheckSummary{Name: "project version", Status: checkOk, Detail: projectVersion.String()})
+	}
+
+	if apiUrl == "" {
+		checks = append(checks, CheckSummary{Name: "api", Status: checkFail, Detail: "no api url, pass -api or -env"})
+	} else if u, err := url.Parse(apiUrl); err != nil || u.Scheme == "" || u.Host == "" {
+		checks = append(checks, CheckSummary{Name: "api", Status: checkFail, Detail: fmt.Sprintf("invalid api url %s", apiUrl)})
+	} else {
+		checks = append(checks, CheckSummary{Name: "api", Status: checkOk, Detail: apiUrl})
+	}
+
+	checks = append(checks, checkToken(currentToken()))
+
+	if platform == "" {
+		checks = append(checks, CheckSummary{Name: "platform", Status: checkWarn, Detail: "no platform, pass -platform"})
+	} else {
+		checks = append(checks, CheckSummary{Name: "platform", Status: checkOk, Detail: platform})
+	}
+
+	return checks
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// destDirStdout is the destination dir value used to stream the downloaded file to stdout
+const destDirStdout = "-"
+
+// entityFileName returns the relative path to save the entity file at, the original path if known or the url file name otherwise
+func entityFileName(file FileMetadata) string {
+	if file.OriginalPath != "" {
+		return filepath.FromSlash(file.OriginalPath)
+	}
+
+	u, err := url.Parse(file.Url)
+	if err != nil {
+		return path.Base(file.Url)
+	}
+	return path.Base(u.Path)
+}
+
+// selectEntityFiles returns the files with the requested original path, file name or type, all files if nothing requested
+func selectEntityFiles(files []FileMetadata, name string) []FileMetadata {
+	if name == "" {
+		return files
+	}
+
+	var selected []FileMetadata
+	for _, file := range files {
+		if file.OriginalPath == name || file.Type == name || entityFileName(file) == filepath.FromSlash(name) {
+			selected = append(selected, file)
+		}
+	}
+	return selected
+}
+
+// Suffix of the partially downloaded file kept between the attempts to resume the download
+const partialDownloadSuffix = ".part"
+
+// requestEntityFile sends the file content request starting at the offset, the response status is 206 if the server resumed the content at the offset
+func requestEntityFile(file FileMetadata, offset int64) (*http.Response, error) {
+	if file.Url == "" {
+		return nil, fmt.Errorf("file has no url")
+	}
+
+	req, err := http.NewRequest("GET", file.Url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the response body: %w", err)
+		}
+		return nil, newApiError("failed to download a file", resp.StatusCode, body)
+	}
+
+	// Make sure the server resumed the content exactly at the requested offset
+	if resp.StatusCode == http.StatusPartialContent && !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected content range '%s' for the offset %d", resp.Header.Get("Content-Range"), offset)
+	}
+
+	return resp, nil
+}
+
+// downloadEntityFile streams the entity file content to the writer
+func downloadEntityFile(file FileMetadata, w io.Writer) (int64, error) {
+	resp, err := requestEntityFile(file, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to write the file content: %w", err)
+	}
+
+	if file.Size != nil && *file.Size != n {
+		return n, fmt.Errorf("downloaded %d bytes, expected %d", n, *file.Size)
+	}
+
+	return n, nil
+}
+
+// downloadEntityFileToDisk downloads the entity file into the destination dir keeping its relative path.
+// The content is written to the .part file first, the download of the existing .part file is resumed with a Range request,
+// the whole file is downloaded again if the server does not support ranges.
+func downloadEntityFileToDisk(file FileMetadata, destDir string) (string, error) {
+	dest := filepath.Join(destDir, entityFileName(file))
+	err := os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create dir: %w", err)
+	}
+
+	partPath := dest + partialDownloadSuffix
+
+	var offset int64
+	if fi, err := os.Stat(partPath); err == nil {
+		offset = fi.Size()
+	}
+
+	// The previous attempt got the whole file but failed the validation
+	if file.Size != nil && offset >= *file.Size {
+		offset = 0
+	}
+
+	resp, err := requestEntityFile(file, offset)
+	if err != nil {
+		return "", err
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 && resp.StatusCode == http.StatusPartialContent {
+		logger.Infof("resuming '%s' download at %d bytes", entityFileName(file), offset)
+		flags = os.O_WRONLY | os.O_APPEND
+	} else {
+		if offset > 0 {
+			logger.Infof("server does not support ranges, downloading '%s' from the start", entityFileName(file))
+		}
+		offset = 0
+	}
+
+	f, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+
+	// Keep the partial content on failure to resume on the next attempt
+	n, err := io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to close file: %w", cerr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write the file content: %w", err)
+	}
+
+	err = validateDownloadedFile(file, partPath, offset+n)
+	if err != nil {
+		// Start from scratch next time as the partial content is corrupted
+		_ = os.Remove(partPath)
+		return "", err
+	}
+
+	err = os.Rename(partPath, dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to rename the downloaded file: %w", err)
+	}
+
+	return dest, nil
+}
+
+// validateDownloadedFile compares the downloaded file size and hash with the file metadata if known
+func validateDownloadedFile(file FileMetadata, path string, size int64) error {
+	if file.Size != nil && *file.Size != size {
+		return fmt.Errorf("downloaded %d bytes, expected %d", size, *file.Size)
+	}
+
+	if file.Hash != nil && *file.Hash != "" {
+		hash, err := computeFileHash(path)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(hash, *file.Hash) {
+			return fmt.Errorf("downloaded file hash %s does not match %s", hash, *file.Hash)
+		}
+	}
+
+	return nil
+}
+
+// downloadEntityFiles downloads the requested entity files into the destination dir, or a single file to stdout if the destination dir is "-"
+func downloadEntityFiles(entityId uuid.UUID, name string, destDir string) error {
+	files, err := getEntityFiles(entityId)
+	if err != nil {
+		return err
+	}
+
+	selected := selectEntityFiles(files, name)
+	if len(selected) == 0 {
+		return fmt.Errorf("no files matching '%s'", name)
+	}
+
+	if destDir == destDirStdout {
+		if len(selected) > 1 {
+			return fmt.Errorf("%d files match '%s', only a single file can be written to stdout", len(selected), name)
+		}
+
+		file := selected[0]
+		n, err := downloadEntityFile(file, os.Stdout)
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("written %d bytes of '%s' to stdout", n, entityFileName(file))
+		summary.addFile(file.Type, destDirStdout, file.Version, retryStats{Attempts: 1})
+		return nil
+	}
+
+	for _, file := range selected {
+		var dest string
+		stats, err := withRetry("download "+entityFileName(file), func() error {
+			var err error
+			dest, err = downloadEntityFileToDisk(file, destDir)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("downloaded '%s'", dest)
+		summary.addFile(file.Type, dest, file.Version, stats)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BuildVersion is the engine Engine/Build/Build.version file
+type BuildVersion struct {
+	MajorVersion int    `json:"MajorVersion"`
+	MinorVersion int    `json:"MinorVersion"`
+	PatchVersion int    `json:"PatchVersion"`
+	BranchName   string `json:"BranchName,omitempty"`
+}
+
+// readBuildVersion reads the engine version from the Build.version file
+func readBuildVersion(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read build version: %w", err)
+	}
+
+	var v BuildVersion
+	err = json.Unmarshal(b, &v)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse build version: %w", err)
+	}
+	if v.MajorVersion == 0 {
+		return "", fmt.Errorf("no major version in %s", path)
+	}
+
+	return fmt.Sprintf("%d.%d.%d", v.MajorVersion, v.MinorVersion, v.PatchVersion), nil
+}
+
+// detectEngineVersion returns the version of the engine the project is built with: the full version from the Build.version of the engine
+// the RunUAT script belongs to if it is found, the launcher engine version the project is associated with otherwise
+func detectEngineVersion(projectName string, uatPath string) (string, error) {
+	if uatPath == "" {
+		if path, err := findUATPath(projectName); err == nil {
+			uatPath = path
+		} else {
+			logger.Debugf("engine not found: %v", err)
+		}
+	}
+
+	// The RunUAT script is in Engine/Build/BatchFiles
+	if uatPath != "" {
+		version, err := readBuildVersion(filepath.Join(filepath.Dir(uatPath), "..", "Build.version"))
+		if err == nil {
+			return version, nil
+		}
+		logger.Debugf("engine build version not found: %v", err)
+	}
+
+	descriptor, err := getProjectDescriptor(projectName)
+	if err != nil {
+		return "", err
+	}
+
+	// Source builds are associated by a GUID which is not a version
+	if !engineVersionAssociationRegexp.MatchString(descriptor.EngineAssociation) {
+		return "", fmt.Errorf("unable to detect the version of the engine '%s' the project is associated with, pass -engineVersion", descriptor.EngineAssociation)
+	}
+
+	return descriptor.EngineAssociation, nil
+}
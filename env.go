@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/ini.v1"
+)
+
+const envDev = "dev"
+const envStaging = "staging"
+const envProd = "prod"
+
+// Section of the config file overriding the environment base urls
+const configEnvironmentsSection = "environments"
+
+var environmentApiUrls = map[string]string{
+	envDev:     "https://api.dev.veverse.com/v2",
+	envStaging: "https://api.staging.veverse.com/v2",
+	envProd:    "https://api.veverse.com/v2",
+}
+
+// resolveEnvironmentApiUrl returns the api base url of the named environment, the config file (if any) takes precedence over the defaults
+func resolveEnvironmentApiUrl(env string, configPath string) (string, error) {
+	url, ok := environmentApiUrls[env]
+	if !ok {
+		return "", fmt.Errorf("unknown environment '%s', expected %s, %s or %s", env, envDev, envStaging, envProd)
+	}
+
+	if configPath != "" {
+		cfg, err := ini.Load(configPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if key := cfg.Section(configEnvironmentsSection).Key(env).String(); key != "" {
+			url = key
+		}
+	}
+
+	return url, nil
+}
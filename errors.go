@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Failure categories the returned errors can be told apart by with errors.Is, the wrapped errors keep their messages
+var (
+	ErrAuth            = errors.New("auth error")
+	ErrNetwork         = errors.New("network error")
+	ErrArchive         = errors.New("archive error")
+	ErrProjectNotFound = errors.New("project not found")
+)
+
+// categorizedError tags the error with the failure category without changing the message
+type categorizedError struct {
+	category error
+	err      error
+}
+
+func (e *categorizedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *categorizedError) Unwrap() error {
+	return e.err
+}
+
+func (e *categorizedError) Is(target error) bool {
+	return target == e.category
+}
+
+// Timeout reports whether the wrapped error is a timeout, the http client and the net.Error checks see through the category
+func (e *categorizedError) Timeout() bool {
+	var t interface{ Timeout() bool }
+	return errors.As(e.err, &t) && t.Timeout()
+}
+
+// Temporary reports whether the wrapped error is temporary
+func (e *categorizedError) Temporary() bool {
+	var t interface{ Temporary() bool }
+	return errors.As(e.err, &t) && t.Temporary()
+}
+
+// withCategory tags the error with the failure category, nil stays nil
+func withCategory(category error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &categorizedError{category: category, err: err}
+}
+
+func (e *ApiError) Is(target error) bool {
+	return target == ErrAuth && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+}
+
+func (e *OAuthError) Is(target error) bool {
+	return target == ErrAuth
+}
+
+func (e *ProjectNotFoundError) Is(target error) bool {
+	return target == ErrProjectNotFound
+}
+
+// exitCodeForError returns the process exit code of the failure category
+func exitCodeForError(err error) int {
+	switch {
+	case errors.Is(err, ErrProjectNotFound):
+		return exitCodeProjectNotFound
+	default:
+		return 1
+	}
+}
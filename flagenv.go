@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Environment variables the flags fall back to if they are not passed, so the CI runners can set their resource limits
+var flagEnvVars = map[string]string{
+	"chunkSize":       "VEVERSE_CHUNK_SIZE",
+	"partConcurrency": "VEVERSE_CONCURRENCY",
+	"retries":         "VEVERSE_RETRIES",
+	"waitTimeout":     "VEVERSE_TIMEOUT",
+}
+
+// applyFlagEnvVars sets the flags not passed on the command line from the environment, returns the applied variables
+func applyFlagEnvVars() ([]string, error) {
+	passed := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+
+	var names []string
+	for name := range flagEnvVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var applied []string
+	for _, name := range names {
+		env := flagEnvVars[name]
+		value := os.Getenv(env)
+		if value == "" || passed[name] {
+			continue
+		}
+
+		err := flag.Set(name, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value '%s': %w", env, value, err)
+		}
+		applied = append(applied, env)
+	}
+
+	return applied, nil
+}
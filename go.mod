@@ -6,6 +6,7 @@ require (
 	github.com/Masterminds/semver/v3 v3.2.1
 	github.com/gabriel-vasile/mimetype v1.4.2
 	github.com/gofrs/uuid v4.4.0+incompatible
+	github.com/klauspost/compress v1.16.5
 	github.com/mholt/archiver/v4 v4.0.0-alpha.8
 	github.com/sirupsen/logrus v1.9.2
 	gopkg.in/ini.v1 v1.67.0
@@ -21,7 +22,6 @@ require (
 	github.com/golang/snappy v0.0.4 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
-	github.com/klauspost/compress v1.16.5 // indirect
 	github.com/klauspost/pgzip v1.2.6 // indirect
 	github.com/nwaples/rardecode/v2 v2.0.0-beta.2 // indirect
 	github.com/pierrec/lz4/v4 v4.1.17 // indirect
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const defaultMaxIdleConns = 100
+const defaultMaxConnsPerHost = 16
+
+// Same as the default limit of the http client
+const defaultMaxRedirects = 10
+
+// httpClient is shared by all the api and storage requests so connections are reused
+var httpClient = &http.Client{}
+
+// newHttpClient creates a client with the connection limits, idle connections are kept for every allowed connection per host so parallel uploads can reuse them.
+// The expired access token acquired with the login task is refreshed transparently.
+func newHttpClient(maxIdleConns int, maxConnsPerHost int, http2 bool, dump bool, maxRedirects int) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxConnsPerHost = maxConnsPerHost
+	if maxConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = maxConnsPerHost
+	}
+
+	transport.ForceAttemptHTTP2 = http2
+	if !http2 {
+		// A non-nil empty map disables the HTTP/2 upgrade
+		transport.TLSNextProto = map[string]func(authority string, c *tls.Conn) http.RoundTripper{}
+	}
+
+	var base http.RoundTripper = transport
+	if dump {
+		base = dumpTransport{base: transport}
+	}
+
+	return &http.Client{Transport: tokenRefreshTransport{base: base}, CheckRedirect: checkRedirect(maxRedirects)}
+}
+
+// checkRedirect returns the redirect policy following up to maxRedirects redirects.
+// The uploads never follow redirects: the client would either drop the body and turn the PUT into a GET or send the large body to an unexpected host.
+func checkRedirect(maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		status := 0
+		if req.Response != nil {
+			status = req.Response.StatusCode
+		}
+		from := urlWithoutQuery(via[len(via)-1].URL)
+		to := urlWithoutQuery(req.URL)
+
+		if via[0].Method == http.MethodPut {
+			return fmt.Errorf("refusing to follow the %d redirect of the upload from %s to %s", status, from, to)
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects, the %d redirect from %s to %s is not followed, check the api url or raise -maxRedirects", maxRedirects, status, from, to)
+		}
+
+		logger.Debugf("following the %d redirect from %s to %s", status, from, to)
+		return nil
+	}
+}
+
+// urlWithoutQuery returns the url without the query and the credentials, the presigned url signatures are not logged
+func urlWithoutQuery(u *url.URL) string {
+	c := *u
+	c.User = nil
+	c.RawQuery = ""
+	return c.String()
+}
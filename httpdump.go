@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+)
+
+// Bodies larger than this are not dumped, e.g. the uploaded and downloaded files
+const maxDumpBodySize = 16 * 1024
+
+// Secrets in the dumped urls, forms and json bodies
+var (
+	dumpAuthorizationRegexp = regexp.MustCompile(`(?im)^(Authorization:\s*\S+\s+)\S+`)
+	dumpQueryRegexp         = regexp.MustCompile(`(?i)((?:X-Amz-Signature|X-Amz-Credential|X-Amz-Security-Token|X-Goog-Signature|X-Goog-Credential|Signature|token|refresh_token|device_code)=)[^&\s"]+`)
+	dumpJsonRegexp          = regexp.MustCompile(`(?i)("(?:access_token|refresh_token|device_code|token)"\s*:\s*")[^"]*`)
+)
+
+// redactDump hides the tokens and the presigned url signatures in the dump
+func redactDump(dump []byte) string {
+	dump = dumpAuthorizationRegexp.ReplaceAll(dump, []byte("${1}[REDACTED]"))
+	dump = dumpQueryRegexp.ReplaceAll(dump, []byte("${1}[REDACTED]"))
+	dump = dumpJsonRegexp.ReplaceAll(dump, []byte("${1}[REDACTED]"))
+	return string(dump)
+}
+
+// isDumpableBody reports whether the body of the known size is small enough to be dumped
+func isDumpableBody(contentLength int64) bool {
+	return contentLength > 0 && contentLength <= maxDumpBodySize
+}
+
+// dumpTransport logs the requests and responses at the debug level, the streamed and large bodies are omitted
+type dumpTransport struct {
+	base http.RoundTripper
+}
+
+func (t dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dump, err := httputil.DumpRequestOut(req, isDumpableBody(req.ContentLength))
+	if err != nil {
+		logger.Debugf("failed to dump request: %v", err)
+	} else {
+		logger.Debugf("http request:\n%s", redactDump(dump))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		logger.Debugf("http request failed: %v", err)
+		return resp, err
+	}
+
+	dump, err = httputil.DumpResponse(resp, isDumpableBody(resp.ContentLength))
+	if err != nil {
+		logger.Debugf("failed to dump response: %v", err)
+	} else {
+		logger.Debugf("http response:\n%s", redactDump(dump))
+	}
+
+	return resp, nil
+}
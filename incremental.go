@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mholt/archiver/v4"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File in the plugin directory storing the time of the last successful upload
+const lastUploadFileName = ".veverse-last-upload"
+
+// readLastUploadTime returns the time of the last successful upload of the plugin, zero if there is none
+func readLastUploadTime(pluginDir string) (time.Time, error) {
+	b, err := os.ReadFile(filepath.Join(pluginDir, lastUploadFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to read last upload time: %w", err)
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse last upload time: %w", err)
+	}
+
+	return t, nil
+}
+
+// writeLastUploadTime stores the time of the successful upload of the plugin
+func writeLastUploadTime(pluginDir string, t time.Time) error {
+	err := os.WriteFile(filepath.Join(pluginDir, lastUploadFileName), []byte(t.Format(time.RFC3339)), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write last upload time: %w", err)
+	}
+	return nil
+}
+
+// filterModifiedFiles keeps the files modified after the time and the directories containing them
+func filterModifiedFiles(files []archiver.File, since time.Time) []archiver.File {
+	dirs := map[string]bool{}
+	for _, file := range files {
+		if !file.IsDir() && file.ModTime().After(since) {
+			for dir := path.Dir(file.NameInArchive); dir != "." && dir != "/"; dir = path.Dir(dir) {
+				dirs[dir] = true
+			}
+		}
+	}
+
+	var result []archiver.File
+	for _, file := range files {
+		if file.IsDir() {
+			if dirs[strings.TrimSuffix(file.NameInArchive, "/")] {
+				result = append(result, file)
+			}
+		} else if file.ModTime().After(since) {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
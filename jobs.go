@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const jobStatusCompleted = "completed"
+const jobStatusError = "error"
+const jobStatusCancelled = "cancelled"
+
+const jobTypePackage = "package"
+
+type JobMetadata struct {
+	Identifier
+	EntityId   *uuid.UUID `json:"entityId,omitempty"`
+	Type       string     `json:"type,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	Platform   string     `json:"platform,omitempty"`
+	Deployment string     `json:"deployment,omitempty"`
+
+	Timestamps
+}
+
+type JobContainer struct {
+	Data JobMetadata `json:"data"`
+}
+
+type JobsContainer struct {
+	Data []JobMetadata `json:"data"`
+}
+
+// isJobFinished reports whether the job reached a terminal status
+func isJobFinished(job JobMetadata) bool {
+	return job.Status == jobStatusCompleted || job.Status == jobStatusError || job.Status == jobStatusCancelled
+}
+
+// getJob fetches the job metadata including its current status
+func getJob(jobId uuid.UUID) (JobMetadata, error) {
+	reqUrl := fmt.Sprintf("%s/jobs/%s", apiUrl, jobId.String())
+
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return JobMetadata{}, fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+	// Process the HTTP request
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return JobMetadata{}, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return JobMetadata{}, fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return JobMetadata{}, newApiError("failed to get job", resp.StatusCode, body)
+	}
+
+	var container JobContainer
+	err = json.Unmarshal(body, &container)
+	if err != nil {
+		return JobMetadata{}, fmt.Errorf("failed to parse job json: %w", err)
+	}
+
+	return container.Data, nil
+}
+
+// getEntityJobs fetches the jobs of the type created for the entity
+func getEntityJobs(entityId uuid.UUID, jobType string) ([]JobMetadata, error) {
+	query := url.Values{"entityId": {entityId.String()}, "type": {jobType}}
+	reqUrl := fmt.Sprintf("%s/jobs?%s", apiUrl, query.Encode())
+
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+	// Process the HTTP request
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, newApiError("failed to get entity jobs", resp.StatusCode, body)
+	}
+
+	var container JobsContainer
+	err = json.Unmarshal(body, &container)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jobs json: %w", err)
+	}
+
+	return container.Data, nil
+}
+
+// findUnfinishedPackageJobs returns the package jobs of the entity still queued or in progress, so a repeated run can attach to them with -attachJob instead of creating duplicates
+func findUnfinishedPackageJobs(entityId uuid.UUID) ([]JobMetadata, error) {
+	jobs, err := getEntityJobs(entityId, jobTypePackage)
+	if err != nil {
+		return nil, err
+	}
+
+	var unfinished []JobMetadata
+	for _, job := range jobs {
+		// Skip the jobs of the other entities in case the api ignores the filter
+		if job.Id == nil || isJobFinished(job) || (job.EntityId != nil && *job.EntityId != entityId) {
+			continue
+		}
+		if job.Type != "" && job.Type != jobTypePackage {
+			continue
+		}
+		unfinished = append(unfinished, job)
+	}
+
+	return unfinished, nil
+}
+
+// waitForJobs polls the jobs status until all of them are finished or the timeout expires, returns an error if any job did not complete successfully
+func waitForJobs(jobs []JobMetadata, interval time.Duration, timeout time.Duration) ([]JobMetadata, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		pending := 0
+		for i, job := range jobs {
+			if job.Id == nil || isJobFinished(job) {
+				continue
+			}
+
+			current, err := getJob(*job.Id)
+			if err != nil {
+				logger.Warningf("failed to get job %s status: %v", job.Id.String(), err)
+				pending++
+				continue
+			}
+
+			if current.Status != job.Status {
+				logger.Infof("job %s (%s) status: %s", job.Id.String(), job.Platform, current.Status)
+			}
+			jobs[i] = current
+
+			if !isJobFinished(current) {
+				pending++
+			}
+		}
+
+		if pending == 0 {
+			break
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return jobs, fmt.Errorf("timed out waiting for %d of %d jobs after %s", pending, len(jobs), timeout)
+		}
+
+		logger.Debugf("waiting for %d of %d jobs", pending, len(jobs))
+		time.Sleep(interval)
+	}
+
+	var failed int
+	for _, job := range jobs {
+		if job.Id == nil {
+			logger.Errorf("job (%s) has no id", job.Platform)
+			failed++
+		} else if job.Status != jobStatusCompleted {
+			logger.Errorf("job %s (%s) %s: %s", job.Id.String(), job.Platform, job.Status, job.Message)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return jobs, fmt.Errorf("%d of %d jobs failed", failed, len(jobs))
+	}
+
+	return jobs, nil
+}
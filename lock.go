@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File in the plugin directory held by the run working with the plugin files
+const lockFileName = ".veverse-lock"
+
+const lockPollInterval = 1 * time.Second
+
+// pluginLockTasks are the tasks writing the plugin temp files and archives
+var pluginLockTasks = map[string]bool{
+	taskUploadPackageSource: true,
+	taskUnzipPackageSource:  true,
+	taskPackagePlugin:       true,
+	taskClean:               true,
+}
+
+// acquirePluginLock creates the lock file in the plugin dir, waiting up to the timeout for another run to release it.
+// The returned func removes the lock file and may be called multiple times.
+func acquirePluginLock(pluginDir string, timeout time.Duration) (func(), error) {
+	path := filepath.Join(pluginDir, lockFileName)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			hostname, _ := os.Hostname()
+			_, err = fmt.Fprintf(f, "pid: %d\nhost: %s\ntime: %s\n", os.Getpid(), hostname, time.Now().Format(time.RFC3339))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				_ = os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock file: %w", err)
+			}
+
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+						logger.Errorf("failed to remove lock file: %v", err)
+					}
+				})
+			}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		if time.Now().Add(lockPollInterval).After(deadline) {
+			holder, _ := os.ReadFile(path)
+			return nil, fmt.Errorf("plugin is locked by another run, remove %s if the run is not active:\n%s", path, holder)
+		}
+
+		logger.Debugf("waiting for the plugin lock %s", path)
+		time.Sleep(lockPollInterval)
+	}
+}
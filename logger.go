@@ -0,0 +1,8 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// logger tags every log line with the run context, it is seeded with the project, plugin, entity and task fields once the flags are parsed
+var logger = logrus.NewEntry(logrus.StandardLogger())
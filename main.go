@@ -2,12 +2,12 @@ package main
 
 import (
 	"bytes"
+	"compress/flate"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/Masterminds/semver/v3"
-	"github.com/gabriel-vasile/mimetype"
 	"github.com/gofrs/uuid"
 	"github.com/mholt/archiver/v4"
 	"github.com/sirupsen/logrus"
@@ -16,19 +16,52 @@ import (
 	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 const taskUploadPackageSource = "uploadPackageSource"
 const taskUnzipPackageSource = "unzipPackageSource"
 const taskUpdateSDK = "updateSDK"
+const taskCheckUpdate = "checkUpdate"
+const taskVerifyRelease = "verifyRelease"
+const taskPackagePlugin = "packagePlugin"
+const taskDownload = "download"
+const taskListFiles = "listFiles"
+const taskDeleteFile = "deleteFile"
+const taskDoctor = "doctor"
+const taskClean = "clean"
+const taskManifest = "manifest"
+const taskUploadFile = "uploadFile"
+const taskLogin = "login"
+const taskPruneReleases = "pruneReleases"
+const taskAbortMultipart = "abortMultipart"
 const minChunkSize = 1 * 1024 * 1024
+const maxChunkSize = 1024 * 1024 * 1024
+const defaultFileFieldName = "file"
+const fileTypeReleaseNotes = "releaseNotes"
+const deploymentServer = "server"
+const deploymentClient = "client"
+const logFormatJson = "json"
+const logFormatText = "text"
+const versionSourceProject = "project"
+const versionSourcePlugin = "plugin"
+
+// Exit code of the runs started outside of the project dir
+const exitCodeProjectNotFound = 3
 
 var (
 	fVerbose   *bool   // Verbose output
+	fQuiet     *bool   // Error output only
 	fLog       *bool   // Create debug log file
 	fApiUrl    *string // APIv2 base url
 	fToken     *string // APIv2 JWT
@@ -38,6 +71,11 @@ var (
 	fEntityId  *string // Entity id
 	fAppId     *string // App id
 	fChunkSize *int64  // Chunk size
+	fLogFormat *string // Log format
+	fRetries   *int    // Number of upload retries
+	fOutput    *string // Summary output format
+	fPlatform  *string // Target platform
+	fDeploy    *string // Deployment type
 	apiUrl     string
 	token      string
 	task       string
@@ -45,14 +83,273 @@ var (
 	project    string
 	entityId   uuid.UUID
 	appId      uuid.UUID
-	chunkSize  int64
+	logFormat  string
+	retries    int
+	output     string
+	platform   string
+	deployment string
+	quiet      bool
+
+	fManifest    *string // Manifest file path
+	manifestPath string
+
+	fCompressionLevel *int // Archive compression level
+	compressionLevel  int
+
+	fReproducible *bool // Create byte identical archives from identical content
+	reproducible  bool
+
+	fAllowVersionMismatch *bool // Warn instead of failing on project and plugin version mismatch
+	allowVersionMismatch  bool
+
+	fEnv    *string // Named API environment
+	fConfig *string // Config file path
+
+	fFollowSymlinks *bool // Archive symlink targets
+	fStoreSymlinks  *bool // Archive symlinks as links
+	symlinkMode     string
+
+	fPackagePath *string // Prebuilt package archive to upload
+	packagePath  string
+
+	fIncremental *bool   // Archive only the files modified since the last upload
+	fSince       *string // Incremental upload start time
+	incremental  bool
+	since        time.Time
+
+	fWait         *bool          // Wait for the package jobs to complete
+	fPollInterval *time.Duration // Job status polling interval
+	fWaitTimeout  *time.Duration // Job completion timeout
+	wait          bool
+	pollInterval  time.Duration
+	waitTimeout   time.Duration
+
+	fResume *bool // Skip the already extracted files
+	resume  bool
+
+	formParams = paramsFlag{} // Extra multipart form params
+
+	fForce *bool // Upload a new version of existing files
+	force  bool
+
+	fAppendToRelease *bool // Add the platform files to an existing release
+	appendToRelease  bool
+
+	fSkipDescriptor *bool // Upload the content only
+	skipDescriptor  bool
+
+	fAllowEmpty *bool // Upload the archive without files
+	allowEmpty  bool
+
+	fMaxSize *int64 // Maximum content and archive size
+	maxSize  int64
+
+	fMaxFiles     *int  // Maximum number of files to archive
+	fMaxFilesWarn *bool // Only warn if the number of files exceeds the limit
+	maxFiles      int
+	maxFilesWarn  bool
+
+	fCommit        *string // Git commit the package was built from
+	fBranch        *string // Git branch the package was built from
+	sourceRevision SourceRevision
+
+	fUpdateConstraint *string // Semver constraint the SDK update must satisfy
+	updateConstraint  *semver.Constraints
+
+	fKeep           *int    // Number of the most recent releases to keep
+	fKeepConstraint *string // Semver constraint of the releases to keep
+	keep            int
+	keepConstraint  *semver.Constraints
+
+	fUploadId         *string // Multipart upload aborted by the abortMultipart task
+	multipartUploadId string
+
+	fNotifyUrl *string // Url to post the run result to
+	notifyUrl  string
+
+	fAuthUrl  *string // OAuth server base url
+	fClientId *string // OAuth client id
+	authUrl   string
+	clientId  string
+
+	fUATPath *string // RunUAT script path
+	uatPath  string
+
+	fArchiveDir    *string // Dir to create the temporary archives in
+	archiveRootDir string
+
+	fEngineVersion *string // Engine version the package is built with
+	engineVersion  string
+
+	includeDirs stringsFlag // Content dirs to package
+	excludeDirs stringsFlag // Content dirs not to package
+
+	fVersion   *bool // Print the build version
+	fListTasks *bool // Print the supported tasks
+
+	fSlowPhase         *time.Duration // Slow phase warning threshold
+	slowPhaseThreshold time.Duration
+
+	fProgressInterval *time.Duration // Minimum interval between the upload progress lines
+	progressInterval  time.Duration
+
+	fAllowMultipartFallback *bool // Upload directly to the API if the presigned upload is unavailable
+	allowMultipartFallback  bool
+
+	fVersionSource *string // Version source, project or plugin
+	versionSource  string
+
+	versionKeys stringsFlag // Fallback ini locations of the project version
+
+	fPluginVersion  *string // Version overriding the detected one
+	versionOverride *semver.Version
+
+	fFileId   *string // Id of the file to delete
+	fFileType *string // Type of the files to delete or upload
+	fYes      *bool   // Skip the confirmation of the deletion or cleanup
+	fileId    uuid.UUID
+	fileType  string
+	yes       bool
+
+	fFilePath     *string // Standalone file to upload
+	fMime         *string // Mime of the uploaded standalone file
+	fOriginalPath *string // Original path of the uploaded standalone file
+	filePath      string
+	fileMime      string
+	originalPath  string
+
+	fReleaseNotes    *string // Release notes file uploaded with the package
+	releaseNotesPath string
+
+	fMimeMap *string // File mapping the extensions to the forced content types
+
+	fMaxIdleConns    *int  // Maximum idle connections
+	fMaxConnsPerHost *int  // Maximum connections per host
+	fHttp2           *bool // Use HTTP/2 when supported
+	fDumpHTTP        *bool // Log the requests and responses
+	fMaxRedirects    *int  // Maximum redirects followed by a request
+	fNoRedirect      *bool // Fail on redirects instead of following them
+
+	fExtractDir         *string // Extraction root relative to the plugin dir or absolute
+	fAllowOutsidePlugin *bool   // Allow extraction outside of the plugin dir
+	extractDir          string
+	allowOutsidePlugin  bool
+
+	fDryRun           *bool // List the artifacts to clean without removing them
+	fCleanExtracted   *bool // Also clean the extracted package content
+	fCleanTempContent *bool // Remove the temp content dir after the upload
+	dryRun            bool
+	cleanExtracted    bool
+	cleanTempContent  bool
+
+	fLockTimeout *time.Duration // Time to wait for another run to release the plugin lock
+	lockTimeout  time.Duration
+
+	fName           *string // Release name
+	fDescription    *string // Release description
+	fMetadataFile   *string // Release name and description json file
+	fPublic         *bool   // Make the release public
+	fPrivate        *bool   // Make the release private
+	releaseMetadata ReleaseMetadata
+
+	fPluginPattern *string // Plugin name glob pattern
+	pluginPattern  string
+
+	fReadBufferSize *int64 // Read buffer size used to stream the uploaded files
+	fPartSize       *int64 // Multipart upload part size requested from the API
+	fReadBuffers    *int   // Number of chunks read ahead while uploading
+	readBufferSize  int64
+	partSize        int64
+	readBuffers     int
+
+	fAutoChunk *bool // Pick the chunk size by the file size
+	autoChunk  bool
+
+	fNoJob *bool // Skip the package job creation
+	noJob  bool
+
+	fAttachJob *bool // Attach to the unfinished package jobs instead of creating new ones
+	attachJob  bool
+
+	contentDirs    stringsFlag // Content dirs to archive
+	includeEntries stringsFlag // Archive entry glob patterns to extract
+	fMergeConflict *string     // Content dirs conflicting files handling
+	mergeConflict  string
+
+	fPartConcurrency *int // Number of parts uploaded in parallel
+	partConcurrency  int
+
+	fHashConcurrency *int // Number of files hashed in parallel
+	hashConcurrency  int
+
+	fSendContentMD5 *bool // Send the Content-MD5 of the body with the direct uploads
+	sendContentMD5  bool
+
+	fDedup *bool // Register the files the API already has instead of uploading them
+	dedup  bool
+
+	fDestDir *string // Download destination dir, "-" for stdout
+	fFile    *string // Downloaded file original path, name or type
+	destDir  string
+	fileName string
 )
 
+// projectTasks are the tasks working with the project files: the tasks writing the plugin files and the ones only reading them
+var projectTasks = withTasks(pluginLockTasks, taskManifest, taskCheckUpdate)
+
+// withTasks returns a copy of the task set including the tasks
+func withTasks(set map[string]bool, tasks ...string) map[string]bool {
+	result := map[string]bool{}
+	for t := range set {
+		result[t] = true
+	}
+	for _, t := range tasks {
+		result[t] = true
+	}
+	return result
+}
+
+// hasTask reports whether the comma separated task list includes the task
+func hasTask(tasks string, task string) bool {
+	for _, t := range strings.Split(tasks, ",") {
+		if t == task {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyTasks reports whether every task of the comma separated task list is one of the tasks
+func onlyTasks(tasks string, allowed ...string) bool {
+	for _, t := range strings.Split(tasks, ",") {
+		found := false
+		for _, a := range allowed {
+			if t == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func errorExit() {
 	flag.Usage()
 	os.Exit(-1)
 }
 
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "  %s -task <task>[,<task>...] [flags]\n\nRuns the tasks in order, the api tasks need the api url (-api or -env) and a token (-token or the login task).\n\nTasks:\n", os.Args[0])
+	printTasks(flag.CommandLine.Output())
+	fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(flag.CommandLine.Output(), "Exit codes:\n  0\tsuccess\n  1\ttask failed\n  %d\tproject dir not found, run from the project dir\n  255\tinvalid arguments\n", exitCodeProjectNotFound)
+}
+
 type Identifier struct {
 	Id *uuid.UUID `json:"id,omitempty"`
 }
@@ -84,6 +381,14 @@ type FileMetadata struct {
 	UpdatedAt    *time.Time `json:"updatedAt,omitempty"`
 	Index        int        `json:"variation,omitempty"`    // variant of the file if applicable (e.g. PDF pages)
 	OriginalPath string     `json:"originalPath,omitempty"` // original relative path to maintain directory structure (e.g. for releases)
+	Hash         *string    `json:"hash,omitempty"`         // hex encoded SHA-256 of the file content if known
+	Provider     string     `json:"provider,omitempty"`     // storage provider of the upload url: s3 (default) or gcs
+	HeadUrl      string     `json:"headUrl,omitempty"`      // url presigned for the HEAD request verifying the uploaded object size
+
+	// The API returns the part urls for the multipart uploads, the url completes the upload then
+	PartSize int64        `json:"partSize,omitempty"` // size of the parts, the last part may be smaller
+	Parts    []UploadPart `json:"parts,omitempty"`
+	UploadId string       `json:"uploadId,omitempty"` // id of the multipart upload to abort it if not completed
 
 	Timestamps
 }
@@ -98,6 +403,12 @@ type EntityMetadata struct {
 	Files []FileMetadata `json:"files,omitempty"`
 }
 
+type EntityMetadataContainer struct {
+	EntityMetadata `json:"data"`
+	Status         string `json:"status,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
 type ReleaseMetadata struct {
 	EntityMetadata
 
@@ -115,6 +426,13 @@ type ReleaseMetadataContainer struct {
 	Message         string `json:"message,omitempty"`
 }
 
+type PluginDescriptor struct {
+	FileVersion  int    `json:"FileVersion"`
+	Version      int    `json:"Version"`
+	VersionName  string `json:"VersionName"`
+	FriendlyName string `json:"FriendlyName"`
+}
+
 func isProjectDir(projectName string, dir string) bool {
 	items, err := os.ReadDir(dir)
 	if err != nil {
@@ -137,6 +455,20 @@ func isProjectDir(projectName string, dir string) bool {
 	return false
 }
 
+// ProjectNotFoundError is returned when no project dir is found walking from the cwd up to the filesystem root
+type ProjectNotFoundError struct {
+	ProjectName string
+	StartDir    string
+}
+
+func (e *ProjectNotFoundError) Error() string {
+	name := "*.uproject"
+	if e.ProjectName != "" {
+		name = e.ProjectName + ".uproject"
+	}
+	return fmt.Sprintf("failed to find the project dir: no %s in %s or any parent dir up to the filesystem root, run from the project dir or check the -project name", name, e.StartDir)
+}
+
 func getProjectDir(projectName string) (string, error) {
 	wd, err := os.Getwd()
 	if err != nil || wd == "" {
@@ -152,7 +484,7 @@ func getProjectDir(projectName string) (string, error) {
 		for {
 			cwd = filepath.Dir(cwd)
 			if cwd == rootDir || cwd == "/" {
-				return "", fmt.Errorf("failed to find the project dir")
+				return "", &ProjectNotFoundError{ProjectName: projectName, StartDir: wd}
 			} else if isProjectDir(projectName, cwd) {
 				return cwd, nil
 			}
@@ -160,10 +492,34 @@ func getProjectDir(projectName string) (string, error) {
 	}
 }
 
+// getProjectName returns the project name from the .uproject file name in the project dir, the -project flag is required if there are several
+func getProjectName(projectDir string) (string, error) {
+	items, err := os.ReadDir(projectDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the project directory: %w", err)
+	}
+
+	var found []string
+	for _, item := range items {
+		if item.IsDir() || strings.ToLower(filepath.Ext(item.Name())) != ".uproject" {
+			continue
+		}
+		found = append(found, strings.TrimSuffix(item.Name(), filepath.Ext(item.Name())))
+	}
+
+	if len(found) == 0 {
+		return "", fmt.Errorf("no .uproject file in %s", projectDir)
+	} else if len(found) > 1 {
+		return "", fmt.Errorf("multiple .uproject files in %s: %s, set the -project name", projectDir, strings.Join(found, ", "))
+	}
+
+	return found[0], nil
+}
+
 func getPluginDir(projectName string, pluginName string) (string, error) {
 	projectDir, err := getProjectDir(projectName)
 	if err != nil {
-		return "", fmt.Errorf("failed to find the plugin directory: %v", err)
+		return "", fmt.Errorf("failed to find the plugin directory: %w", err)
 	}
 
 	pluginDir := filepath.Join(projectDir, "Plugins", pluginName)
@@ -171,10 +527,36 @@ func getPluginDir(projectName string, pluginName string) (string, error) {
 	return pluginDir, nil
 }
 
+// getPluginDescriptorPath looks for the plugin .uplugin file in the plugin dir ignoring the name case, returns the path with the actual on-disk name
+func getPluginDescriptorPath(pluginDir string, pluginName string) (string, error) {
+	items, err := os.ReadDir(pluginDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the plugin directory: %w", err)
+	}
+
+	var found []string
+	for _, item := range items {
+		if item.IsDir() || strings.ToLower(filepath.Ext(item.Name())) != ".uplugin" {
+			continue
+		}
+
+		if strings.EqualFold(item.Name(), pluginName+".uplugin") {
+			return filepath.Join(pluginDir, item.Name()), nil
+		}
+		found = append(found, item.Name())
+	}
+
+	if len(found) == 0 {
+		return "", fmt.Errorf("no .uplugin file found in %s", pluginDir)
+	}
+
+	return "", fmt.Errorf("no %s.uplugin file found in %s, found: %s", pluginName, pluginDir, strings.Join(found, ", "))
+}
+
 func getPluginTempDir(projectName string, pluginName string) (string, error) {
 	projectDir, err := getProjectDir(projectName)
 	if err != nil {
-		return "", fmt.Errorf("failed to find the plugin directory: %v", err)
+		return "", fmt.Errorf("failed to find the plugin directory: %w", err)
 	}
 
 	pluginDir := filepath.Join(projectDir, "Plugins", pluginName, "Temp", pluginName)
@@ -189,98 +571,196 @@ func getProjectVersion(projectName string) (version *semver.Version, err error)
 
 	// Get the project directory
 	if projectDir, err = getProjectDir(projectName); err != nil {
-		return nil, fmt.Errorf("failed to get project version: %v", err)
+		return nil, fmt.Errorf("failed to get project version: %w", err)
+	}
+
+	// Check the locations in order, the missing files and the empty keys fall back to the next one
+	var checked []string
+	for _, location := range projectVersionLocations {
+		checked = append(checked, location.String())
+
+		path := filepath.Join(projectDir, "Config", location.File)
+		if _, err = os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		var cfg *ini.File
+		cfg, err = ini.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ini: %w", err)
+		}
+
+		versionKey := strings.TrimSpace(cfg.Section(location.Section).Key(location.Key).String())
+		if versionKey == "" {
+			continue
+		}
+
+		version, err = semver.NewVersion(versionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version %s: %w", location, err)
+		}
+
+		if location != defaultVersionLocation {
+			logger.Infof("using the project version %s from %s", version, location)
+		}
+		return version, nil
+	}
+
+	return nil, fmt.Errorf("no project version found, checked: %s", strings.Join(checked, ", "))
+}
+
+func getPluginVersion(projectName string, pluginName string) (version *semver.Version, err error) {
+	var (
+		pluginDir string
+	)
+
+	// Get the plugin directory
+	if pluginDir, err = getPluginDir(projectName, pluginName); err != nil {
+		return nil, fmt.Errorf("failed to get plugin version: %w", err)
+	}
+
+	// Find and parse the plugin descriptor
+	descriptorPath, err := getPluginDescriptorPath(pluginDir, pluginName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plugin version: %w", err)
+	}
+
+	b, err := os.ReadFile(descriptorPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin descriptor: %w", err)
 	}
 
-	// Find and load the ini file
-	var cfg *ini.File
-	cfg, err = ini.Load(filepath.Join(projectDir, "Config", "DefaultGame.ini"))
+	var descriptor PluginDescriptor
+	err = json.Unmarshal(b, &descriptor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load ini: %v", err)
+		return nil, fmt.Errorf("failed to parse plugin descriptor: %w", err)
 	}
 
-	// Get the project version key
-	versionKey := cfg.Section("/Script/EngineSettings.GeneralProjectSettings").Key("ProjectVersion").String()
-	version, err = semver.NewVersion(versionKey)
+	version, err = semver.NewVersion(descriptor.VersionName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse version: %v", err)
+		return nil, fmt.Errorf("failed to parse version: %w", err)
 	}
 
 	return version, nil
 }
 
+// getVersion reads the version from the project config ini files or the plugin descriptor depending on the source
+func getVersion(projectName string, pluginName string, source string) (*semver.Version, error) {
+	switch source {
+	case versionSourceProject:
+		return getProjectVersion(projectName)
+	case versionSourcePlugin:
+		return getPluginVersion(projectName, pluginName)
+	default:
+		return nil, fmt.Errorf("unknown version source '%s', expected %s or %s", source, versionSourceProject, versionSourcePlugin)
+	}
+}
+
+// checkVersionMismatch compares the project and plugin versions, returns an error if they differ.
+// The check is skipped with a warning if either version is unavailable.
+func checkVersionMismatch(projectName string, pluginName string) error {
+	projectVersion, err := getProjectVersion(projectName)
+	if err != nil {
+		logger.Warningf("skipping the version check, the project version is unavailable: %v", err)
+		return nil
+	}
+
+	pluginVersion, err := getPluginVersion(projectName, pluginName)
+	if err != nil {
+		logger.Warningf("skipping the version check, the plugin version is unavailable: %v", err)
+		return nil
+	}
+
+	if !projectVersion.Equal(pluginVersion) {
+		return fmt.Errorf("project version %s does not match plugin version %s", projectVersion.String(), pluginVersion.String())
+	}
+
+	return nil
+}
+
 // fetchUnclaimedJob Tries to fetch the unclaimed job supported by the runner, validates and returns it
-func getLatestVersion() (version *semver.Version, err error) {
+func getLatestVersion(platform string) (version *semver.Version, err error) {
 	// Prepare an HTTP request
-	reqUrl := fmt.Sprintf("%s/apps/%s/releases/latest?platform=%s", apiUrl, appId)
+	reqUrl := fmt.Sprintf("%s/apps/%s/releases/latest?platform=%s", apiUrl, appId, url.QueryEscape(platform))
 	req, err := http.NewRequest("GET", reqUrl, nil)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
 
 	// Send HTTP request
-	client := &http.Client{}
+	client := httpClient
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %s", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	// Process response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %s", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Validate response
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("failed to fetch an unclaimed job, status code: %d, content: %s", resp.StatusCode, string(body))
+		return nil, newApiError("failed to get the latest release", resp.StatusCode, body)
 	}
 
 	// Parse the HTTP request json content
 	var container ReleaseMetadataContainer
 	err = json.Unmarshal(body, &container)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse job json: %s", err.Error())
+		return nil, fmt.Errorf("failed to parse job json: %w", err)
 	}
 
 	version, err = semver.NewVersion(container.ReleaseMetadata.Version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create semver: %v", err)
+		return nil, fmt.Errorf("failed to create semver: %w", err)
 	}
 
 	return version, nil
 }
 
-// uploadFile uploads the job results to the API for storage
-func uploadEntityFile(entityId uuid.UUID, fileType string, fileMime string, path string, originalPath string, params map[string]string) error {
-	const chunkSize = 100 * 1024 * 1024 // 100MiB
+// getLatestVersions returns the latest release versions keyed by platform
+func getLatestVersions(platforms []string) (map[string]*semver.Version, error) {
+	versions := map[string]*semver.Version{}
+	for _, p := range platforms {
+		if _, ok := versions[p]; ok {
+			continue
+		}
+
+		version, err := getLatestVersion(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the latest version for platform '%s': %w", p, err)
+		}
+		versions[p] = version
+	}
+
+	return versions, nil
+}
+
+// uploadFile uploads the job results to the API for storage, the query params are added to the url and the params are sent as the multipart form fields
+func uploadEntityFile(entityId uuid.UUID, fileType string, fileMime string, path string, originalPath string, query map[string]string, params map[string]string, fieldName string) error {
+	if fieldName == "" {
+		fieldName = defaultFileFieldName
+	}
 
 	if entityId.IsNil() {
 		return fmt.Errorf("invalid job package id")
 	}
 
 	// Warning! For the package upload we don't set index and original-path to prevent duplicates, if these fields provided, we will get an error on DB index in future re-uploads of the package
-	reqUrl := fmt.Sprintf("%s/entities/%s/files/upload?type=%s&mime=%s&original-path=%s", apiUrl, entityId.String(), fileType, fileMime, originalPath)
+	reqUrl := fmt.Sprintf("%s/entities/%s/files/upload?type=%s&mime=%s&original-path=%s", apiUrl, entityId.String(), url.QueryEscape(fileType), url.QueryEscape(fileMime), url.QueryEscape(originalPath))
 
-	// Open file
-	file, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+	// Add query parameters if any supplied
+	for key, value := range query {
+		reqUrl += fmt.Sprintf("&%s=%s", key, url.QueryEscape(value))
 	}
 
 	// Get file info
-	fi, err := file.Stat()
+	fi, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %v", err)
+		return fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	// Defer file close
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			logrus.Errorf("failed to close the uploading package file")
-		}
-	}(file)
-
 	// Temporary buffer to get multipart form fields (header) and the boundary
 	multipartFormBuffer := &bytes.Buffer{}
 
@@ -288,12 +768,15 @@ func uploadEntityFile(entityId uuid.UUID, fileType string, fileMime string, path
 	multipartFormWriter := multipart.NewWriter(multipartFormBuffer)
 	for key, value := range params {
 		err = multipartFormWriter.WriteField(key, value)
+		if err != nil {
+			return fmt.Errorf("failed to write form field %s: %w", key, err)
+		}
 	}
 
-	// Add a file to the multipart form writer, the field name should be "file" as the API expects it
-	_, err = multipartFormWriter.CreateFormFile("file", fi.Name())
+	// Add a file to the multipart form writer, the field name is configurable and defaults to "file" expected by the entity file upload endpoint
+	_, err = multipartFormWriter.CreateFormFile(fieldName, fi.Name())
 	if err != nil {
-		return fmt.Errorf("failed to create a multipart form file: %v", err)
+		return fmt.Errorf("failed to create a multipart form file: %w", err)
 	}
 
 	// Get multipart form content type including boundary
@@ -305,7 +788,7 @@ func uploadEntityFile(entityId uuid.UUID, fileType string, fileMime string, path
 	multipartFormOpeningHeader := make([]byte, multipartFormOpeningHeaderSize)
 	_, err = multipartFormBuffer.Read(multipartFormOpeningHeader)
 	if err != nil {
-		return fmt.Errorf("failed to read the multipart form buffer: %v", err)
+		return fmt.Errorf("failed to read the multipart form buffer: %w", err)
 	}
 
 	// Write the multipart form closing boundary to the buffer
@@ -323,232 +806,405 @@ func uploadEntityFile(entityId uuid.UUID, fileType string, fileMime string, path
 		return fmt.Errorf("failed to read boundary from the multipart form buffer")
 	}
 
+	// Hash the multipart body before streaming it, the header must be sent before the body, so this costs an extra full read of the file
+	var contentMD5 string
+	if sendContentMD5 {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		contentMD5, err = computeContentMD5(io.MultiReader(bytes.NewReader(multipartFormOpeningHeader), file, bytes.NewReader(multipartFormClosingBoundary)))
+		_ = file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Calculate the total content size including opening header size, uploaded file size and closing boundary length
 	multipartDataTotalSize := int64(multipartFormOpeningHeaderSize) + fi.Size() + int64(multipartFormClosingBoundarySize)
 
-	// Use a pipe to write request data
-	pipeReader, pipeWriter := io.Pipe()
-	defer func(rd *io.PipeReader) {
-		err := rd.Close()
+	// newBody re-opens the file and streams the multipart form from scratch, so every attempt of the request gets a complete body
+	newBody := func() (io.ReadCloser, error) {
+		// Open file
+		file, err := os.Open(path)
 		if err != nil {
-			logrus.Errorf("failed to close a pipe reader")
+			return nil, fmt.Errorf("failed to open file: %w", err)
 		}
-	}(pipeReader)
-
-	var totalSent = 0
-
-	go func() {
-		defer func(pipeWriter *io.PipeWriter) {
-			err := pipeWriter.Close()
-			if err != nil {
-				logrus.Errorf("failed to close a pipe writer: %v", err)
-			}
-		}(pipeWriter)
 
-		// Write the multipart form opening header
-		_, err = pipeWriter.Write(multipartFormOpeningHeader)
+		// Make sure the precomputed content length is still valid
+		current, err := file.Stat()
 		if err != nil {
-			logrus.Errorf("failed to write the opening header to the multipart form: %v", err)
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to stat file: %w", err)
+		}
+		if current.Size() != fi.Size() {
+			_ = file.Close()
+			return nil, fmt.Errorf("file size changed from %d to %d bytes", fi.Size(), current.Size())
 		}
 
-		// Write the file bytes to the temporary buffer
-		buffer := make([]byte, chunkSize)
-		for {
-			n, err := file.Read(buffer)
-			if err != nil {
-				if err != io.EOF {
-					logrus.Errorf("failed to read from the file pipe reader: %v", err)
-				}
-				break
-			}
+		// Use a pipe to write request data
+		pipeReader, pipeWriter := io.Pipe()
 
-			logrus.Debugf("sending bytes '%d' to '%d'", totalSent, totalSent+n)
-			totalSent += n
+		go func() {
+			// Defer file close
+			defer func(file *os.File) {
+				err := file.Close()
+				if err != nil {
+					logger.Errorf("failed to close the uploading package file")
+				}
+			}(file)
 
-			_, err = pipeWriter.Write(buffer[:n])
+			// Close the pipe passing the write error (if any) to the request
+			err := writeMultipartBody(pipeWriter, file, multipartFormOpeningHeader, multipartFormClosingBoundary)
 			if err != nil {
-				logrus.Errorf("failed to write file bytes to the multipart form: %v", err)
+				logger.Errorf("failed to write the multipart form: %v", err)
 			}
-		}
+			_ = pipeWriter.CloseWithError(err)
+		}()
 
-		// Write the closing boundary to the multipart form
-		_, err = pipeWriter.Write(multipartFormClosingBoundary)
-		if err != nil {
-			logrus.Errorf("failed to write the closing boundary to the multipart form: %v", err)
-		}
-	}()
+		return pipeReader, nil
+	}
+
+	body, err := newBody()
+	if err != nil {
+		return err
+	}
 
 	// Create an HTTP request with the pipe reader
-	req, err := http.NewRequest("PUT", reqUrl, pipeReader)
+	req, err := http.NewRequest("PUT", reqUrl, body)
+	if err != nil {
+		_ = body.Close()
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.GetBody = newBody
 	req.Header.Set("Content-Type", multipartFormDataContentType)
 	req.ContentLength = multipartDataTotalSize
+	if contentMD5 != "" {
+		req.Header.Set("Content-MD5", contentMD5)
+	}
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
 
 	// Process the HTTP request
-	client := &http.Client{}
+	client := httpClient
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return fmt.Errorf("failed to send request: %w", err)
 	}
 
 	defer func(body io.ReadCloser) {
 		err := body.Close()
 		if err != nil {
-			logrus.Errorf("failed to close resp body: %v", err)
+			logger.Errorf("failed to close resp body: %v", err)
 		}
 	}(resp.Body)
 
 	if resp.StatusCode >= 400 {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return fmt.Errorf("failed to read the response body: %v", err)
+			return fmt.Errorf("failed to read the response body: %w", err)
 		}
-		return fmt.Errorf("failed to upload a file, status code: %d, content: %s", resp.StatusCode, string(body))
+		return newApiError("failed to upload a file", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// writeMultipartBody writes the multipart form opening header, the file content by chunks and the closing boundary
+func writeMultipartBody(w io.Writer, file io.Reader, openingHeader []byte, closingBoundary []byte) error {
+	// Write the multipart form opening header
+	_, err := w.Write(openingHeader)
+	if err != nil {
+		return fmt.Errorf("failed to write the opening header to the multipart form: %w", err)
+	}
+
+	// Write the file bytes by chunks
+	var totalSent = 0
+	err = copyChunks(w, file, func(n int) {
+		logger.Debugf("sending bytes '%d' to '%d'", totalSent, totalSent+n)
+		totalSent += n
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write the file to the multipart form: %w", err)
+	}
+
+	// Write the closing boundary to the multipart form
+	_, err = w.Write(closingBoundary)
+	if err != nil {
+		return fmt.Errorf("failed to write the closing boundary to the multipart form: %w", err)
 	}
 
 	return nil
 }
 
 type EntityUploadUrlPayload struct {
-	Data FileMetadata `json:"data,omitempty"`
+	Data    FileMetadata `json:"data,omitempty"`
+	Status  string       `json:"status,omitempty"`
+	Message string       `json:"message,omitempty"`
+}
+
+// validate checks the upload url response has the required file id and url
+func (p EntityUploadUrlPayload) validate() error {
+	var missing []string
+	if p.Data.Id == nil || p.Data.Id.IsNil() {
+		missing = append(missing, "id")
+	}
+	if p.Data.Url == "" {
+		missing = append(missing, "url")
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if p.Status != "" || p.Message != "" {
+		return fmt.Errorf("malformed upload url response, missing %s, status: %s, message: %s", strings.Join(missing, ", "), p.Status, p.Message)
+	}
+	return fmt.Errorf("malformed upload url response, missing %s", strings.Join(missing, ", "))
+}
+
+// uploadUrlParams returns the upload url query parameters common to all uploaded files, tagged with the release version if known
+func uploadUrlParams(releaseVersion *semver.Version) map[string]string {
+	params := map[string]string{}
+	if releaseVersion != nil {
+		params["release-version"] = releaseVersion.String()
+	}
+	if platform != "" {
+		params["platform"] = platform
+	}
+	if deployment != "" {
+		params["deployment-type"] = deployment
+	}
+	if partSize != 0 {
+		params["part-size"] = strconv.FormatInt(partSize, 10)
+	}
+	return params
 }
 
-func getEntityFileUploadUrl(entityId uuid.UUID, fileType string, mime string, size int64, originalPath string) (FileMetadata, error) {
-	reqUrl := fmt.Sprintf("%s/files/upload?entityId=%s&type=%s&mime=%s&size=%d&original-path=%s", apiUrl, entityId.String(), fileType, mime, size, originalPath)
+func getEntityFileUploadUrl(entityId uuid.UUID, fileType string, mime string, size int64, originalPath string, params map[string]string) (FileMetadata, error) {
+	reqUrl := fmt.Sprintf("%s/files/upload?entityId=%s&type=%s&mime=%s&size=%d&original-path=%s", apiUrl, entityId.String(), url.QueryEscape(fileType), url.QueryEscape(mime), size, url.QueryEscape(originalPath))
+
+	// Add query parameters if any supplied
+	for key, value := range params {
+		reqUrl += fmt.Sprintf("&%s=%s", key, url.QueryEscape(value))
+	}
 
 	req, err := http.NewRequest("GET", reqUrl, nil)
 	if err != nil {
-		return FileMetadata{}, fmt.Errorf("failed to instantiate request: %v", err)
+		return FileMetadata{}, fmt.Errorf("failed to instantiate request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
 
 	// Process the HTTP request
-	client := &http.Client{}
+	client := httpClient
 	resp, err := client.Do(req)
 	if err != nil {
-		return FileMetadata{}, fmt.Errorf("failed to send request: %v", err)
+		return FileMetadata{}, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	defer func(body io.ReadCloser) {
 		err := body.Close()
 		if err != nil {
-			logrus.Errorf("failed to close resp body: %v", err)
+			logger.Errorf("failed to close resp body: %v", err)
 		}
 	}(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return FileMetadata{}, fmt.Errorf("failed to read the response body: %v", err)
+		return FileMetadata{}, fmt.Errorf("failed to read the response body: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return FileMetadata{}, fmt.Errorf("failed to upload a file, status code: %d, content: %s", resp.StatusCode, string(body))
+		return FileMetadata{}, newApiError("failed to get an upload url", resp.StatusCode, body)
 	}
 
 	var container EntityUploadUrlPayload
 	err = json.Unmarshal(body, &container)
 	if err != nil {
-		return FileMetadata{}, fmt.Errorf("failed to parse upload URL json: %s", err.Error())
+		return FileMetadata{}, fmt.Errorf("failed to parse upload URL json: %w", err)
+	}
+
+	err = container.validate()
+	if err != nil {
+		return FileMetadata{}, err
 	}
 
 	return container.Data, nil
 }
 
-func createPackageJobs(entityId uuid.UUID) error {
-	reqUrl := fmt.Sprintf("%s/jobs/package", apiUrl)
-
-	m := map[string]string{"entityId": entityId.String()}
-	b, err := json.Marshal(m)
-	if err != nil {
-		return fmt.Errorf("failed to serialize entity id: %v", err)
-	}
+// getEntityFiles fetches the metadata of the files attached to the entity
+func getEntityFiles(entityId uuid.UUID) ([]FileMetadata, error) {
+	reqUrl := fmt.Sprintf("%s/entities/%s", apiUrl, entityId.String())
 
-	req, err := http.NewRequest("POST", reqUrl, bytes.NewReader(b))
+	req, err := http.NewRequest("GET", reqUrl, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to instantiate request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
 
 	// Process the HTTP request
-	client := &http.Client{}
+	client := httpClient
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	defer func(body io.ReadCloser) {
 		err := body.Close()
 		if err != nil {
-			logrus.Errorf("failed to close resp body: %v", err)
+			logger.Errorf("failed to close resp body: %v", err)
 		}
 	}(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read the response body: %v", err)
+		return nil, fmt.Errorf("failed to read the response body: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("failed to upload a file, status code: %d, content: %s", resp.StatusCode, string(body))
+		return nil, newApiError("failed to get entity files", resp.StatusCode, body)
 	}
 
-	return nil
-}
+	var container EntityMetadataContainer
+	err = json.Unmarshal(body, &container)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse entity json: %w", err)
+	}
 
-func logUploadStatus(current int64, total int64) {
-	logrus.Infof("u%d:%d|%.3f", current, total, float64(current)/float64(total))
+	return container.Files, nil
 }
 
-// uploadFile uploads the job results to the API for storage
-func uploadEntityFileToS3(presignedUrl string, entityId uuid.UUID, path string) error {
-	if entityId.IsNil() {
-		return fmt.Errorf("invalid job package id")
+func createPackageJobs(entityId uuid.UUID, releaseVersion *semver.Version, label ReleaseMetadata, revision SourceRevision, engineVersion string, releaseNotesId *uuid.UUID, idempotencyKey string) ([]JobMetadata, error) {
+	reqUrl := fmt.Sprintf("%s/jobs/package", apiUrl)
+
+	m := map[string]interface{}{"entityId": entityId.String()}
+	if releaseVersion != nil {
+		m["version"] = releaseVersion.String()
+	}
+	if label.Name != nil && *label.Name != "" {
+		m["name"] = *label.Name
+	}
+	if label.Description != nil && *label.Description != "" {
+		m["description"] = *label.Description
+	}
+	if revision.Commit != "" {
+		m["commit"] = revision.Commit
+	}
+	if revision.Branch != "" {
+		m["branch"] = revision.Branch
+	}
+	if engineVersion != "" {
+		m["engineVersion"] = engineVersion
+	}
+	if releaseNotesId != nil {
+		m["releaseNotesFileId"] = releaseNotesId.String()
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize package job request: %w", err)
 	}
 
-	// Open file
-	file, err := os.Open(path)
+	req, err := http.NewRequest("POST", reqUrl, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+	// The same key is sent with the repeated requests so the api can deduplicate the jobs
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	// Process the HTTP request
+	client := httpClient
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	// Get file info
-	fi, err := file.Stat()
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %v", err)
+		return nil, fmt.Errorf("failed to read the response body: %w", err)
 	}
 
-	fileTotalSize := fi.Size()
+	if resp.StatusCode >= 400 {
+		return nil, newApiError("failed to create package job", resp.StatusCode, body)
+	}
+
+	var container JobsContainer
+	err = json.Unmarshal(body, &container)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jobs json: %w", err)
+	}
+
+	return container.Data, nil
+}
+
+// Time of the last logged upload progress line, parts of the multipart uploads report the progress concurrently
+var (
+	uploadStatusMu       sync.Mutex
+	uploadStatusReported time.Time
+)
+
+// logUploadStatus logs the upload progress at most once per progress interval and always on completion, every chunk is logged in the verbose mode
+func logUploadStatus(current int64, total int64) {
+	if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		uploadStatusMu.Lock()
+		defer uploadStatusMu.Unlock()
+
+		now := time.Now()
+		if current < total && now.Sub(uploadStatusReported) < progressInterval {
+			return
+		}
+		uploadStatusReported = now
+	}
+
+	reportProgress(progressUpload, current, total)
+}
 
-	//region Determine MIME type
+// uploadEntityFileToStorage uploads the file to the url of the file metadata using the storage backend of its provider,
+// the content type must match the mime the url was requested with
+func uploadEntityFileToStorage(metadata FileMetadata, entityId uuid.UUID, path string, contentType string) error {
+	if entityId.IsNil() {
+		return fmt.Errorf("invalid job package id")
+	}
 
-	// Seek back to the start of the file
-	_, err = file.Seek(0, io.SeekStart)
+	storage, err := storageBackendFor(metadata)
 	if err != nil {
-		return fmt.Errorf("failed to rewind file before mime detection: %v", err)
+		return err
 	}
 
-	// Try to detect MIME
-	pMIME, _ := mimetype.DetectReader(file)
-	fileContentType := pMIME.String()
+	// Open file
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
 
-	// Seek back to the start of the file
-	_, err = file.Seek(0, io.SeekStart)
+	// Get file info
+	fi, err := file.Stat()
 	if err != nil {
-		logrus.Errorf("failed to rewind file after mime detection: %v", err)
+		return fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	//endregion
+	fileTotalSize := fi.Size()
 
 	// Defer file close
 	defer func(file *os.File) {
 		err := file.Close()
 		if err != nil {
-			logrus.Errorf("failed to close the uploading package file: %v", err)
+			logger.Errorf("failed to close the uploading package file: %v", err)
 		}
 	}(file)
 
@@ -557,75 +1213,50 @@ func uploadEntityFileToS3(presignedUrl string, entityId uuid.UUID, path string)
 	defer func(rd *io.PipeReader) {
 		err := rd.Close()
 		if err != nil {
-			logrus.Errorf("failed to close a pipe reader")
+			logger.Errorf("failed to close a pipe reader")
 		}
 	}(pipeReader)
 
-	var totalSent int64 = 0
-
 	go func() {
 		defer func(pipeWriter *io.PipeWriter) {
 			err := pipeWriter.Close()
 			if err != nil {
-				logrus.Errorf("failed to close a pipe writer: %v", err)
+				logger.Errorf("failed to close a pipe writer: %v", err)
 			}
 		}(pipeWriter)
 
-		// Write the file bytes to the temporary buffer
-		buffer := make([]byte, chunkSize)
-		for {
-			n, err := file.Read(buffer)
-			if err != nil {
-				if err != io.EOF {
-					logrus.Errorf("failed to read from the file pipe reader: %v", err)
-				}
-				break
-			}
-
-			_, err = pipeWriter.Write(buffer[:n])
-			if err != nil {
-				logrus.Errorf("failed to write file bytes to the multipart form: %v", err)
-			}
-
+		// Write the file bytes to the pipe by chunks
+		var totalSent int64 = 0
+		err := copyChunks(pipeWriter, file, func(n int) {
 			totalSent += int64(n)
 			logUploadStatus(totalSent, fileTotalSize)
+		})
+		if err != nil {
+			logger.Errorf("failed to write the file to the pipe: %v", err)
 		}
 	}()
 
-	logrus.Debugf("uploading to: %s", presignedUrl)
+	logger.Debugf("uploading to: %s", metadata.Url)
 
-	// Create an HTTP request with the pipe reader
-	req, err := http.NewRequest("PUT", presignedUrl, pipeReader)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+	// Remember the multipart upload to abort it if the run is interrupted
+	if len(metadata.Parts) > 0 && metadata.UploadId != "" {
+		trackMultipartUpload(entityId, metadata.UploadId)
 	}
-	req.Header.Set("Content-Type", fileContentType)
-	req.ContentLength = fileTotalSize
-	req.Header.Set("Accept", "application/json")
 
-	// Process the HTTP request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+	err = storage.Upload(context.Background(), metadata.Url, pipeReader, fileTotalSize, contentType)
+	if err == nil && metadata.UploadId != "" {
+		untrackMultipartUpload(metadata.UploadId)
 	}
+	return err
+}
 
-	defer func(body io.ReadCloser) {
-		err := body.Close()
-		if err != nil {
-			logrus.Errorf("failed to close resp body: %v", err)
-		}
-	}(resp.Body)
-
-	if resp.StatusCode >= 400 {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read the response body: %v", err)
-		}
-		return fmt.Errorf("failed to upload a file, status code: %d, content: %s", resp.StatusCode, string(body))
+// isTerminal reports whether the file is attached to a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
 	}
-
-	return nil
+	return fi.Mode()&os.ModeCharDevice != 0
 }
 
 func init() {
@@ -633,36 +1264,447 @@ func init() {
 }
 
 func main() {
+	flag.Usage = usage
 	fVerbose = flag.Bool("v", false, "verbose")
+	fQuiet = flag.Bool("quiet", false, "log errors only, the json summary is still printed with -output json, can't be used with -v")
 	fLog = flag.Bool("log", false, "logging")
 	fApiUrl = flag.String("api", "", "api base url")
-	fToken = flag.String("token", "", "authentication token")
-	fTask = flag.String("task", "", "supported types: uploadPackageSource")
+	fToken = flag.String("token", "", "authentication token, the token acquired with the login task is used if empty")
+	fTask = flag.String("task", "", "comma separated tasks to run in order, supported types: "+strings.Join(taskNames(), ", ")+", see -listTasks")
 	fPlugin = flag.String("plugin", "", "plugin name")
-	fProject = flag.String("project", "", "project name")
+	fProject = flag.String("project", "", "project name, inferred from the .uproject file name in the project dir if empty")
 	fEntityId = flag.String("entityId", "", "entity id")
 	fAppId = flag.String("appId", "", "app id")
-	fChunkSize = flag.Int64("chunkSize", 0, "chunk size")
+	fChunkSize = flag.Int64("chunkSize", 0, "chunk size in bytes used to stream uploaded files, between 1MiB (default) and 1GiB, sets -readBufferSize if it is not set, VEVERSE_CHUNK_SIZE by default")
+	fReadBufferSize = flag.Int64("readBufferSize", 0, "read buffer size in bytes used to stream uploaded files, between 1MiB (default) and 1GiB")
+	fReadBuffers = flag.Int("readBuffers", 2, "number of read buffers, the next chunks are read from the disk while the current one is sent, 1 to read and send sequentially")
+	fPartSize = flag.Int64("partSize", 0, "multipart upload part size in bytes requested from the api, between 1MiB and 5GiB, the api picks the size by default")
+	fLogFormat = flag.String("logFormat", "", "log format: json or text (default text when stdout is a terminal, json otherwise)")
+	fRetries = flag.Int("retries", 3, "number of retries for failed uploads, VEVERSE_RETRIES by default")
+	fOutput = flag.String("output", outputText, "summary output format: text or json")
+	fPlatform = flag.String("platform", "", "target platform of the uploaded files, e.g. Win64 or Mac, comma separated platforms for the checkUpdate task")
+	fDeploy = flag.String("deployment", "", "deployment type of the uploaded files: server or client")
+	fManifest = flag.String("manifest", "", "path to the manifest of the uploaded files, written on upload and read on release verification, the manifest task writes the content manifest to stdout if empty")
+	fReproducible = flag.Bool("reproducible", false, "sort the archive entries and normalize their timestamps and permissions, so the same content always produces the same archive")
+	fCompressionLevel = flag.Int("compressionLevel", flate.DefaultCompression, "archive deflate compression level from 0 (store) to 9 (best), -1 for the default level")
+	fAllowVersionMismatch = flag.Bool("allowVersionMismatch", false, "warn instead of failing when the project and plugin versions differ")
+	fEnv = flag.String("env", "", "named api environment: dev, staging or prod, -api takes precedence")
+	fConfig = flag.String("config", "", "path to the ini config file, the [environments] section overrides the environment api urls")
+	fFollowSymlinks = flag.Bool("followSymlinks", false, "archive the files symlinks point to, symlinks are skipped by default")
+	fStoreSymlinks = flag.Bool("storeSymlinks", false, "archive symlinks as links, symlinks are skipped by default")
+	fPackagePath = flag.String("packagePath", "", "upload the prebuilt zip archive instead of archiving the plugin content, - reads the archive from stdin")
+	fIncremental = flag.Bool("incremental", false, "archive and upload only the content modified since the last successful upload")
+	fSince = flag.String("since", "", "RFC 3339 time to archive the modified content from, implies -incremental")
+	fWait = flag.Bool("wait", false, "wait for the created package jobs to complete")
+	fPollInterval = flag.Duration("pollInterval", 10*time.Second, "package job status polling interval")
+	fWaitTimeout = flag.Duration("waitTimeout", 2*time.Hour, "maximum time to wait for the package jobs to complete, VEVERSE_TIMEOUT by default")
+	fResume = flag.Bool("resume", false, "skip the archive entries already extracted with the same size and checksum")
+	flag.Var(formParams, "param", "extra key=value multipart form field sent with the direct file uploads, repeatable, e.g. -param version=2 -param index=0")
+	fCommit = flag.String("commit", "", "git commit sha the package was built from, sent with the package jobs and written to the manifest, detected from the CI environment (e.g. GITHUB_SHA) by default")
+	fBranch = flag.String("branch", "", "git branch the package was built from, sent with the package jobs and written to the manifest, detected from the CI environment (e.g. GITHUB_REF) by default")
+	fUploadId = flag.String("uploadId", "", "id of the multipart upload aborted by the abortMultipart task, all the incomplete multipart uploads of the entity by default")
+	fKeep = flag.Int("keep", 10, "number of the most recent releases kept by the pruneReleases task")
+	fKeepConstraint = flag.String("keepConstraint", "", "semver constraint of the releases always kept by the pruneReleases task, e.g. >=1.0.0 <1.1.0")
+	fUpdateConstraint = flag.String("updateConstraint", "", "semver constraint the latest SDK version must satisfy to update to it, checked by the checkUpdate task, e.g. ~1.2.0 to stay on 1.2.x or ^1.0.0 to stay on 1.x")
+	fNotifyUrl = flag.String("notifyUrl", "", "url to post the json result (entity id, plugin, version, status, duration, file count) to when the run succeeds or fails, e.g. a chat webhook")
+	fAuthUrl = flag.String("authUrl", "", "oauth server base url with the /device/code and /token endpoints used by the login task")
+	fClientId = flag.String("clientId", defaultClientId, "oauth client id used by the login task")
+	fMaxFiles = flag.Int("maxFiles", 0, "abort the upload if there are more content files to archive, 0 for no limit")
+	fMaxFilesWarn = flag.Bool("maxFilesWarn", false, "only warn if the number of content files exceeds -maxFiles")
+	fMaxSize = flag.Int64("maxSize", 0, "abort the upload if the content or the archive is larger than the size in bytes, 0 for no limit")
+	fSkipDescriptor = flag.Bool("skipDescriptor", false, "upload the package content only, keeping the .uplugin descriptor already uploaded to the entity")
+	fAllowEmpty = flag.Bool("allowEmpty", false, "upload the package even if there are no content files to archive")
+	fAppendToRelease = flag.Bool("appendToRelease", false, "add the -platform and -deployment tagged package to the existing release entity, keeping its descriptor and the other platform files, the release version is taken from -versionSource or -pluginVersion")
+	fForce = flag.Bool("force", false, "upload a new version of the files already existing for the entity instead of failing")
+	fUATPath = flag.String("uatPath", "", "path to the RunUAT script, discovered from the project engine association by default")
+	fArchiveDir = flag.String("archiveDir", "", "dir to write the temporary package archive to, the OS temp dir by default, the archive is deleted after the upload")
+	fEngineVersion = flag.String("engineVersion", "", "engine version the package is built with, sent with the package jobs and written to the manifest, detected from the engine Build.version or the project engine association by default")
+	flag.Var(&includeDirs, "includeDir", "content dir to package relative to the plugin content temp dir, repeatable, all dirs are packaged by default")
+	flag.Var(&excludeDirs, "excludeDir", "content dir not to package relative to the plugin content temp dir, repeatable, in addition to the ones listed in the plugin .upluginignore file")
+	fVersion = flag.Bool("version", false, "print the tool version and exit")
+	fListTasks = flag.Bool("listTasks", false, "print the supported tasks with their descriptions and required flags and exit")
+	fDestDir = flag.String("destDir", ".", "dir to download the entity files to, - to write a single file to stdout and logs to stderr")
+	fProgressInterval = flag.Duration("progressInterval", 500*time.Millisecond, "minimum interval between the upload progress log lines, every chunk is logged with -v or 0")
+	fSlowPhase = flag.Duration("slowPhase", 30*time.Minute, "warn when an upload phase (archive, presign, upload, job-create) takes longer, 0 to disable")
+	fAllowMultipartFallback = flag.Bool("allowMultipartFallback", false, "upload the content directly to the api if the presigned upload endpoint is unavailable")
+	fPluginVersion = flag.String("pluginVersion", "", "semver the upload url, the package jobs and the uploadFile files are tagged with instead of the -versionSource version, e.g. 1.2.3+build.45 for the nightly builds")
+	flag.Var(&versionKeys, "versionKey", "fallback location of the project version as section:key:file with the file in the project Config dir, e.g. /Script/EngineSettings.GeneralProjectSettings:ProjectVersion:DefaultEngine.ini, repeatable, checked in order if the DefaultGame.ini ProjectVersion is empty or missing")
+	fVersionSource = flag.String("versionSource", "", "read the version from the project DefaultGame.ini (project) or the .uplugin VersionName (plugin), the upload url and the package jobs are tagged with the version if set")
+	fMaxIdleConns = flag.Int("maxIdleConns", defaultMaxIdleConns, "maximum number of idle keep-alive connections across all hosts, 0 for no limit")
+	fMaxConnsPerHost = flag.Int("maxConnsPerHost", defaultMaxConnsPerHost, "maximum number of connections per host including active ones, also the number of idle connections kept per host, 0 for no limit")
+	fHttp2 = flag.Bool("http2", true, "use HTTP/2 when the server supports it, -http2=false forces HTTP/1.1")
+	fMaxRedirects = flag.Int("maxRedirects", defaultMaxRedirects, "maximum number of redirects followed by an api request, the uploads never follow redirects")
+	fNoRedirect = flag.Bool("noRedirect", false, "fail on the redirects instead of following them, same as -maxRedirects 0")
+	fDumpHTTP = flag.Bool("dumpHTTP", false, "log every http request and response with the headers and small bodies at the debug level (-v), the tokens and url signatures are redacted")
+	fExtractDir = flag.String("extractDir", defaultExtractDir, "dir to extract the package content to, relative to the plugin dir or absolute")
+	fAllowOutsidePlugin = flag.Bool("allowOutsidePlugin", false, "allow the extract dir outside of the plugin dir")
+	fLockTimeout = flag.Duration("lockTimeout", 0, "time to wait for another run to release the plugin lock, fails immediately by default")
+	fName = flag.String("name", "", "release name sent with the package jobs")
+	fDescription = flag.String("description", "", "release description sent with the package jobs")
+	fMetadataFile = flag.String("metadataFile", "", "json file with the release name, description and public visibility, the flags take precedence")
+	fPublic = flag.Bool("public", false, "make the release public, the visibility is kept unchanged if neither -public nor -private is set")
+	fPrivate = flag.Bool("private", false, "make the release private, can't be used with -public")
+	fPluginPattern = flag.String("pluginPattern", "", "run the tasks for every project plugin with a .uplugin matching the glob pattern, e.g. VeVerse*, instead of -plugin")
+	fAutoChunk = flag.Bool("autoChunk", false, "pick the read buffer size and the multipart upload part size by the uploaded file size, overrides -chunkSize, an explicit -partSize is kept")
+	fNoJob = flag.Bool("noJob", false, "upload the package without creating the package jobs")
+	fAttachJob = flag.Bool("attachJob", false, "attach to the queued or running package jobs of the entity instead of creating new ones, e.g. to repeat a run that failed after the job creation, the attached jobs package the content they were created for")
+	flag.Var(&includeEntries, "include", "glob pattern of the archive entries to extract with unzipPackageSource, repeatable, an entry matches if its path or any of its parent dirs matches, e.g. Content/Textures or Content/*.uasset, all entries by default")
+	flag.Var(&contentDirs, "contentDir", "dir to archive relative to the plugin content temp dir or absolute, repeatable, files are archived under the dir name, all the content temp dir items by default")
+	fMergeConflict = flag.String("mergeConflict", mergeConflictError, "handling of the files existing in multiple content dirs: error or overwrite (the last dir wins)")
+	fDedup = flag.Bool("dedup", false, "ask the api for a file with the same SHA-256 before uploading and register it for the entity instead of uploading on a hit, the content archive only matches with -reproducible")
+	fSendContentMD5 = flag.Bool("sendContentMD5", false, "send the Content-MD5 of the multipart request body with the direct api uploads, requires an extra full read of the file before the upload")
+	fHashConcurrency = flag.Int("hashConcurrency", runtime.NumCPU(), "number of content files hashed in parallel for the archive manifest, the number of CPUs by default")
+	fPartConcurrency = flag.Int("partConcurrency", 4, "number of multipart upload parts uploaded in parallel, each holds a part in memory, VEVERSE_CONCURRENCY by default")
+	fFileId = flag.String("fileId", "", "id of the entity file to delete")
+	fFileType = flag.String("type", "", "type of the entity files to delete or of the uploaded file, e.g. uplugin_content or image_preview")
+	fReleaseNotes = flag.String("releaseNotes", "", "markdown or text release notes file uploaded with the package as the releaseNotes file and referenced by the package jobs")
+	fFilePath = flag.String("filePath", "", "path to the file to attach to the entity with the uploadFile task")
+	fMimeMap = flag.String("mimeMap", "", "file mapping the file extensions to the uploaded content types, one \"extension mime\" pair per line, used instead of the content detection")
+	fMime = flag.String("mime", "", "mime of the file uploaded with the uploadFile task, detected from the content by default")
+	fOriginalPath = flag.String("originalPath", "", "original path of the file uploaded with the uploadFile task, the file name by default")
+	fYes = flag.Bool("yes", false, "delete, clean or abort the multipart uploads without asking for confirmation, delete the releases selected by the pruneReleases task instead of listing them")
+	fDryRun = flag.Bool("dryRun", false, "list the generated artifacts the clean task or -cleanTempContent would remove and the multipart uploads the abortMultipart task would abort without removing them")
+	fCleanExtracted = flag.Bool("cleanExtracted", false, "also remove the extracted package content (-extractDir) with the clean task, only if the dir was created by the unzipPackageSource task")
+	fCleanTempContent = flag.Bool("cleanTempContent", false, "remove the Temp/<plugin> content dir after the successful upload to reclaim the disk space")
+	fFile = flag.String("file", "", "original path, name or type of the entity file to download, all files by default")
 	flag.Parse()
 
+	envSettings, err := applyFlagEnvVars()
+	if err != nil {
+		logger.Errorf("%v", err)
+		errorExit()
+	}
+
+	if fVersion != nil && *fVersion {
+		printVersion()
+		os.Exit(0)
+	}
+	if fListTasks != nil && *fListTasks {
+		printTasks(os.Stdout)
+		os.Exit(0)
+	}
+
+	if fDestDir != nil {
+		destDir = *fDestDir
+	}
+	if fMaxIdleConns == nil || *fMaxIdleConns < 0 || fMaxConnsPerHost == nil || *fMaxConnsPerHost < 0 || fHttp2 == nil || fDumpHTTP == nil {
+		errorExit()
+	}
+	if fMaxRedirects == nil || *fMaxRedirects < 0 {
+		errorExit()
+	}
+	maxRedirects := *fMaxRedirects
+	if fNoRedirect != nil && *fNoRedirect {
+		maxRedirects = 0
+	}
+	httpClient = newHttpClient(*fMaxIdleConns, *fMaxConnsPerHost, *fHttp2, *fDumpHTTP, maxRedirects)
+
+	if fExtractDir == nil || *fExtractDir == "" {
+		errorExit()
+	}
+	extractDir = *fExtractDir
+	if fAllowOutsidePlugin != nil {
+		allowOutsidePlugin = *fAllowOutsidePlugin
+	}
+
+	if fMergeConflict == nil || (*fMergeConflict != mergeConflictError && *fMergeConflict != mergeConflictOverwrite) {
+		errorExit()
+	}
+	mergeConflict = *fMergeConflict
+
+	if fPartConcurrency == nil || *fPartConcurrency < 1 {
+		errorExit()
+	}
+	partConcurrency = *fPartConcurrency
+
+	if fHashConcurrency == nil || *fHashConcurrency < 1 {
+		errorExit()
+	}
+	hashConcurrency = *fHashConcurrency
+
+	if fSendContentMD5 != nil {
+		sendContentMD5 = *fSendContentMD5
+	}
+	if fDedup != nil {
+		dedup = *fDedup
+	}
+
+	if fAutoChunk != nil {
+		autoChunk = *fAutoChunk
+	}
+
+	for _, pattern := range includeEntries {
+		if _, err := path.Match(pattern, ""); err != nil {
+			logger.Errorf("invalid include pattern '%s': %v", pattern, err)
+			errorExit()
+		}
+	}
+
+	if fPluginPattern != nil && *fPluginPattern != "" {
+		pluginPattern = *fPluginPattern
+		if _, err := filepath.Match(pluginPattern, ""); err != nil {
+			logger.Errorf("invalid plugin pattern: %v", err)
+			errorExit()
+		}
+	}
+
+	if fLockTimeout == nil || *fLockTimeout < 0 {
+		errorExit()
+	}
+	lockTimeout = *fLockTimeout
+
+	if fMetadataFile != nil && *fMetadataFile != "" {
+		var err error
+		releaseMetadata, err = readReleaseMetadata(*fMetadataFile)
+		if err != nil {
+			logger.Errorf("invalid release metadata: %v", err)
+			errorExit()
+		}
+	}
+	if fName != nil && *fName != "" {
+		releaseMetadata.Name = fName
+	}
+	if fDescription != nil && *fDescription != "" {
+		releaseMetadata.Description = fDescription
+	}
+	if fPublic != nil && fPrivate != nil && *fPublic && *fPrivate {
+		logger.Errorf("-public and -private can't be used together")
+		errorExit()
+	}
+	if fPublic != nil && *fPublic {
+		public := true
+		releaseMetadata.Public = &public
+	} else if fPrivate != nil && *fPrivate {
+		public := false
+		releaseMetadata.Public = &public
+	}
+	if err := releaseMetadata.validate(); err != nil {
+		logger.Errorf("invalid release metadata: %v", err)
+		errorExit()
+	}
+	if releaseMetadata.Name != nil {
+		summary.Name = *releaseMetadata.Name
+	}
+	if releaseMetadata.Description != nil {
+		summary.Description = *releaseMetadata.Description
+	}
+
+	if fFileId != nil && *fFileId != "" {
+		fileId = uuid.FromStringOrNil(*fFileId)
+		if fileId.IsNil() {
+			logger.Errorf("invalid file id '%s'", *fFileId)
+			errorExit()
+		}
+	}
+	if fFileType != nil {
+		fileType = *fFileType
+	}
+	if fFilePath != nil {
+		filePath = *fFilePath
+	}
+	if fMime != nil {
+		fileMime = *fMime
+	}
+	if fOriginalPath != nil {
+		originalPath = *fOriginalPath
+	}
+	if fReleaseNotes != nil && *fReleaseNotes != "" {
+		releaseNotesPath = *fReleaseNotes
+		fi, err := os.Stat(releaseNotesPath)
+		if err != nil {
+			logger.Errorf("invalid release notes: %v", err)
+			errorExit()
+		}
+		if fi.IsDir() || fi.Size() == 0 {
+			logger.Errorf("invalid release notes: %s is a directory or an empty file", releaseNotesPath)
+			errorExit()
+		}
+	}
+	if fMimeMap != nil && *fMimeMap != "" {
+		mimeOverrides, err = readMimeMap(*fMimeMap)
+		if err != nil {
+			logger.Errorf("invalid mime map: %v", err)
+			errorExit()
+		}
+	}
+	if fYes != nil {
+		yes = *fYes
+	}
+	if fDryRun != nil {
+		dryRun = *fDryRun
+	}
+	if fCleanExtracted != nil {
+		cleanExtracted = *fCleanExtracted
+	}
+	if fCleanTempContent != nil {
+		cleanTempContent = *fCleanTempContent
+	}
+
+	if fVersionSource != nil {
+		versionSource = *fVersionSource
+	}
+	if versionSource != "" && versionSource != versionSourceProject && versionSource != versionSourcePlugin {
+		logger.Errorf("invalid version source '%s', expected %s or %s", versionSource, versionSourceProject, versionSourcePlugin)
+		errorExit()
+	}
+	for _, value := range versionKeys {
+		location, err := parseVersionLocation(value)
+		if err != nil {
+			logger.Errorf("%v", err)
+			errorExit()
+		}
+		projectVersionLocations = append(projectVersionLocations, location)
+	}
+	if fPluginVersion != nil && *fPluginVersion != "" {
+		var err error
+		versionOverride, err = semver.StrictNewVersion(*fPluginVersion)
+		if err != nil {
+			logger.Errorf("invalid plugin version '%s', expected a semver like 1.2.3, 1.2.3-rc.1 or 1.2.3+build.45: %v", *fPluginVersion, err)
+			errorExit()
+		}
+	}
+
+	if fAllowMultipartFallback != nil {
+		allowMultipartFallback = *fAllowMultipartFallback
+	}
+	if fSlowPhase != nil {
+		slowPhaseThreshold = *fSlowPhase
+	}
+	if fProgressInterval == nil || *fProgressInterval < 0 {
+		errorExit()
+	}
+	progressInterval = *fProgressInterval
+	if fFile != nil {
+		fileName = *fFile
+	}
+
+	// Keep stdout for the downloaded file content or the generated manifest only
+	stdoutContent := destDir == destDirStdout || (fManifest != nil && *fManifest == "" && fTask != nil && hasTask(*fTask, taskManifest))
+	if stdoutContent {
+		logrus.SetOutput(os.Stderr)
+		summaryOutput = os.Stderr
+	}
+
+	if fLogFormat != nil && *fLogFormat != "" {
+		logFormat = *fLogFormat
+	} else if isTerminal(os.Stdout) {
+		logFormat = logFormatText
+	} else {
+		logFormat = logFormatJson
+	}
+
+	switch logFormat {
+	case logFormatJson:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	case logFormatText:
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		errorExit()
+	}
+
+	if fQuiet != nil {
+		quiet = *fQuiet
+	}
+	if quiet && fVerbose != nil && *fVerbose {
+		logger.Errorf("-quiet and -v are mutually exclusive")
+		errorExit()
+	}
+
 	if fVerbose != nil && *fVerbose {
 		logrus.SetLevel(logrus.DebugLevel)
+	} else if quiet {
+		logrus.SetLevel(logrus.ErrorLevel)
 	}
 
 	if fLog != nil && *fLog {
 		f, err := os.OpenFile("metaverse-sdk-automation.log", os.O_WRONLY|os.O_CREATE, 0755)
 		if err != nil {
-			logrus.Fatalf("failed to open log file")
+			logger.Fatalf("failed to open log file")
+		}
+		var out io.Writer = os.Stdout
+		if stdoutContent {
+			out = os.Stderr
 		}
-		mw := io.MultiWriter(os.Stdout, f)
+		mw := io.MultiWriter(out, f)
 		logrus.SetOutput(mw)
 	}
 
+	// The diagnostics report the missing settings instead of failing
+	diagnose := fTask != nil && *fTask == taskDoctor
+	if fNotifyUrl != nil {
+		notifyUrl = *fNotifyUrl
+	}
+
+	var commit, branch string
+	if fCommit != nil {
+		commit = *fCommit
+	}
+	if fBranch != nil {
+		branch = *fBranch
+	}
+	sourceRevision = detectSourceRevision(commit, branch)
+
+	if fUploadId != nil {
+		multipartUploadId = *fUploadId
+	}
+
+	if fKeep == nil || *fKeep < 0 {
+		errorExit()
+	}
+	keep = *fKeep
+	if fKeepConstraint != nil && *fKeepConstraint != "" {
+		var err error
+		keepConstraint, err = semver.NewConstraint(*fKeepConstraint)
+		if err != nil {
+			logger.Errorf("invalid keep constraint: %v", err)
+			errorExit()
+		}
+	}
+
+	if fUpdateConstraint != nil && *fUpdateConstraint != "" {
+		var err error
+		updateConstraint, err = semver.NewConstraint(*fUpdateConstraint)
+		if err != nil {
+			logger.Errorf("invalid update constraint: %v", err)
+			errorExit()
+		}
+	}
+
+	// The login acquires the token for the other tasks
+	loggingIn := fTask != nil && *fTask == taskLogin
+	if loggingIn {
+		if fAuthUrl == nil || *fAuthUrl == "" {
+			logger.Errorf("-authUrl is required to log in")
+			errorExit()
+		}
+		authUrl = *fAuthUrl
+		if fClientId == nil || *fClientId == "" {
+			errorExit()
+		}
+		clientId = *fClientId
+	}
+
 	if fApiUrl == nil {
 		errorExit()
 	}
 	apiUrl = *fApiUrl
-	if apiUrl == "" {
+	if apiUrl == "" && fEnv != nil && *fEnv != "" {
+		var err error
+		var configPath string
+		if fConfig != nil {
+			configPath = *fConfig
+		}
+
+		apiUrl, err = resolveEnvironmentApiUrl(*fEnv, configPath)
+		if err != nil {
+			logger.Errorf("failed to resolve environment: %v", err)
+			errorExit()
+		}
+		logger.Infof("using '%s' environment api: %s", *fEnv, apiUrl)
+	} else if fEnv != nil && *fEnv != "" {
+		logger.Infof("using custom api %s instead of the '%s' environment", apiUrl, *fEnv)
+	}
+	if apiUrl == "" && !diagnose && !loggingIn {
 		errorExit()
 	}
 
@@ -670,8 +1712,14 @@ func main() {
 		errorExit()
 	}
 	token = *fToken
-	if token == "" {
-		errorExit()
+	if token == "" && !loggingIn {
+		// Use the token acquired with the login task
+		var err error
+		token, err = cachedToken()
+		if err != nil && !diagnose {
+			logger.Errorf("%v", err)
+			errorExit()
+		}
 	}
 
 	if fEntityId == nil {
@@ -679,13 +1727,15 @@ func main() {
 	}
 
 	entityId = uuid.FromStringOrNil(*fEntityId)
-	if entityId.IsNil() {
+	// The releases are pruned and the updates are checked by the app, the entity is not needed if all the tasks are app tasks
+	appTasksOnly := fTask != nil && onlyTasks(*fTask, taskPruneReleases, taskCheckUpdate)
+	if entityId.IsNil() && !diagnose && !loggingIn && !appTasksOnly {
 		errorExit()
 	}
 
 	appId = uuid.FromStringOrNil(*fAppId)
 	if appId.IsNil() {
-		logrus.Warningf("no app id")
+		logger.Warningf("no app id")
 		//errorExit()
 	}
 
@@ -699,123 +1749,738 @@ func main() {
 	}
 	plugin = *fPlugin
 
-	pluginDir, err := getPluginDir(project, plugin)
-	if err != nil {
-		logrus.Fatalf("failed to get plugin dir: %v", err)
+	var chunkSize int64
+	if fChunkSize != nil && *fChunkSize != 0 {
+		if *fChunkSize < minChunkSize || *fChunkSize > maxChunkSize {
+			logger.Errorf("invalid chunk size %d, expected a value between %d and %d bytes", *fChunkSize, minChunkSize, maxChunkSize)
+			errorExit()
+		}
+		chunkSize = *fChunkSize
 	}
 
-	pluginContentTempDir, err := getPluginTempDir(project, plugin)
-	if err != nil {
-		logrus.Fatalf("failed to get plugin temp dir: %v", err)
+	// The chunk size is used for the read buffer unless it is set explicitly
+	readBufferSize = chunkSize
+	if fReadBufferSize != nil && *fReadBufferSize != 0 {
+		readBufferSize = *fReadBufferSize
+	}
+	if readBufferSize == 0 {
+		readBufferSize = minChunkSize
+	} else if readBufferSize < minChunkSize || readBufferSize > maxChunkSize {
+		logger.Errorf("invalid read buffer size %d, expected a value between %d and %d bytes", readBufferSize, minChunkSize, maxChunkSize)
+		errorExit()
 	}
 
-	if fChunkSize != nil && *fChunkSize > minChunkSize {
-		chunkSize = *fChunkSize
-	} else {
-		chunkSize = minChunkSize
+	if fReadBuffers == nil || *fReadBuffers < 1 || *fReadBuffers > maxReadBuffers {
+		logger.Errorf("invalid read buffer count, expected a value between 1 and %d", maxReadBuffers)
+		errorExit()
 	}
+	readBuffers = *fReadBuffers
 
-	if fTask == nil {
+	// Only an explicit part size is requested from the api, it picks the part size otherwise
+	if fPartSize != nil {
+		partSize = *fPartSize
+	}
+	if partSize != 0 && (partSize < minChunkSize || partSize > maxPartSize) {
+		logger.Errorf("invalid part size %d, expected a value between %d and %d bytes", partSize, minChunkSize, int64(maxPartSize))
 		errorExit()
 	}
-	task = *fTask
-	switch task {
-	case taskUploadPackageSource:
-		{
-			logrus.Debugf("uploading '%s' package descriptor", plugin)
-			upluginName := filepath.Join(pluginDir, plugin+".uplugin")
-			err = uploadEntityFile(entityId, "uplugin", "application/json", upluginName, plugin+".uplugin", nil)
-			if err != nil {
-				logrus.Fatalf("failed to upload entity file: %v", err)
-			}
 
-			logrus.Debugf("compressing '%s' package content", plugin)
-			zipName := filepath.Join(pluginDir, plugin+".zip")
-			zip, err := os.Create(zipName)
-			if err != nil {
-				logrus.Fatalf("failed to create a zip file: %v", err)
-			}
-			defer func(zip *os.File) {
-				err := zip.Close()
-				if err != nil {
-					logrus.Errorf("failed to close a zip file: %v", err)
-				}
+	if fRetries == nil || *fRetries < 0 {
+		errorExit()
+	}
+	retries = *fRetries
 
-				// delete zip file after upload
-				err = os.Remove(zipName)
-				if err != nil {
-					logrus.Errorf("failed to delete zip file: %v", err)
-				}
-			}(zip)
+	if fOutput == nil {
+		errorExit()
+	}
+	output = *fOutput
+	if output != outputText && output != outputJson {
+		errorExit()
+	}
 
-			format := archiver.CompressedArchive{
-				Archival: archiver.Zip{},
-			}
+	if fPlatform != nil {
+		platform = *fPlatform
+	}
 
-			var archiveFileMap = map[string]string{}
+	if fDeploy != nil {
+		deployment = *fDeploy
+	}
+	if deployment != "" && deployment != deploymentServer && deployment != deploymentClient {
+		logger.Errorf("invalid deployment type '%s', expected %s or %s", deployment, deploymentServer, deploymentClient)
+		errorExit()
+	}
 
-			items, err := os.ReadDir(pluginContentTempDir)
-			if err != nil {
-				logrus.Fatalf("failed to read content dir: %v", err)
-			}
-			for _, item := range items {
-				itemPath := filepath.Join(pluginContentTempDir, item.Name())
-				archiveFileMap[itemPath] = ""
-			}
+	if fManifest != nil {
+		manifestPath = *fManifest
+	}
 
-			releaseArchiveFiles, err := archiver.FilesFromDisk(nil, archiveFileMap)
-			if err != nil {
-				logrus.Fatalf("failed to enumerate release archive files to zip: %v", err)
-			}
+	if fCompressionLevel == nil || *fCompressionLevel < flate.DefaultCompression || *fCompressionLevel > flate.BestCompression {
+		errorExit()
+	}
+	compressionLevel = *fCompressionLevel
+	if fReproducible != nil {
+		reproducible = *fReproducible
+	}
+
+	if fAllowVersionMismatch != nil {
+		allowVersionMismatch = *fAllowVersionMismatch
+	}
+
+	symlinkMode = symlinksSkip
+	if fFollowSymlinks != nil && *fFollowSymlinks {
+		symlinkMode = symlinksFollow
+	}
+	if fStoreSymlinks != nil && *fStoreSymlinks {
+		if symlinkMode == symlinksFollow {
+			logger.Errorf("-followSymlinks and -storeSymlinks are mutually exclusive")
+			errorExit()
+		}
+		symlinkMode = symlinksStore
+	}
+
+	if fIncremental != nil {
+		incremental = *fIncremental
+	}
+	if fSince != nil && *fSince != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, *fSince)
+		if err != nil {
+			logger.Errorf("invalid -since time: %v", err)
+			errorExit()
+		}
+		incremental = true
+	}
+	if fPackagePath != nil {
+		packagePath = *fPackagePath
+	}
+	// The archived file times and order make every content archive unique unless it is reproducible
+	if dedup && !reproducible && packagePath == "" && fTask != nil && hasTask(*fTask, taskUploadPackageSource) {
+		logger.Warningf("-dedup without -reproducible never matches the content archive, pass -reproducible to deduplicate the unchanged content")
+	}
+	if packagePath != "" && incremental {
+		logger.Errorf("-packagePath can't be used with -incremental")
+		errorExit()
+	}
+
+	if fWait != nil {
+		wait = *fWait
+	}
+	if fPollInterval == nil || *fPollInterval <= 0 {
+		errorExit()
+	}
+	pollInterval = *fPollInterval
+	if fWaitTimeout == nil || *fWaitTimeout <= 0 {
+		errorExit()
+	}
+	waitTimeout = *fWaitTimeout
+
+	if fNoJob != nil {
+		noJob = *fNoJob
+	}
+	if fAttachJob != nil {
+		attachJob = *fAttachJob
+	}
+	if noJob && wait {
+		logger.Errorf("-noJob and -wait are mutually exclusive")
+		errorExit()
+	}
+
+	if fResume != nil {
+		resume = *fResume
+	}
+
+	if fForce != nil {
+		force = *fForce
+	}
+	if fAllowEmpty != nil {
+		allowEmpty = *fAllowEmpty
+	}
+	if fSkipDescriptor != nil {
+		skipDescriptor = *fSkipDescriptor
+	}
+	if fMaxSize == nil || *fMaxSize < 0 {
+		errorExit()
+	}
+	maxSize = *fMaxSize
+	if fMaxFiles == nil || *fMaxFiles < 0 {
+		errorExit()
+	}
+	maxFiles = *fMaxFiles
+	if fMaxFilesWarn != nil {
+		maxFilesWarn = *fMaxFilesWarn
+	}
+	if fAppendToRelease != nil {
+		appendToRelease = *fAppendToRelease
+	}
+	if appendToRelease && platform == "" {
+		logger.Errorf("-appendToRelease requires -platform to tell the release files apart")
+		errorExit()
+	}
+	if appendToRelease && versionSource == "" && versionOverride == nil {
+		logger.Errorf("-appendToRelease requires -versionSource or -pluginVersion to tell the release the files are added to")
+		errorExit()
+	}
+
+	if fUATPath != nil {
+		uatPath = *fUATPath
+	}
+	if fEngineVersion != nil {
+		engineVersion = *fEngineVersion
+	}
+	if fArchiveDir != nil && *fArchiveDir != "" {
+		archiveRootDir = *fArchiveDir
+		if fi, err := os.Stat(archiveRootDir); err != nil || !fi.IsDir() {
+			logger.Errorf("archive dir %s is not a directory", archiveRootDir)
+			errorExit()
+		}
+	}
+
+	if fTask == nil {
+		errorExit()
+	}
+
+	// Tag all the following log lines with the run context
+	logger = logrus.WithFields(logrus.Fields{
+		"task":     *fTask,
+		"project":  project,
+		"plugin":   plugin,
+		"entityId": entityId.String(),
+	})
+
+	if len(envSettings) > 0 {
+		logger.Infof("using %s from the environment", strings.Join(envSettings, ", "))
+	}
+	logger.Infof("settings: read buffer size %d, part size %d, part concurrency %d, retries %d, wait timeout %s", readBufferSize, partSize, partConcurrency, retries, waitTimeout)
+
+	tasks := strings.Split(*fTask, ",")
+
+	// Run the exit handlers releasing the lock and removing the temp files on interrupt
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logger.Warningf("received %s, exiting", sig)
+		abortActiveMultipartUploads()
+		logrus.Exit(1)
+	}()
+
+	// Fail early with a distinct exit code if the tasks require the project
+	for _, t := range tasks {
+		if !projectTasks[t] && pluginPattern == "" {
+			continue
+		}
+
+		projectDir, err := getProjectDir(project)
+		if err != nil {
+			logger.Errorf("%v", err)
+			logrus.Exit(exitCodeForError(err))
+		}
 
-			err = format.Archive(context.Background(), zip, releaseArchiveFiles)
+		// Use the discovered project name if not set explicitly
+		if project == "" {
+			project, err = getProjectName(projectDir)
 			if err != nil {
-				logrus.Fatalf("failed to zip release archive files: %v", err)
+				logger.Fatalf("failed to infer the project name: %v", err)
 			}
+			logger = logger.WithField("project", project)
+			logger.Infof("using project '%s' from %s", project, projectDir)
+		}
+		break
+	}
+
+	summary.Task = *fTask
+	// Report the files uploaded and deleted before the failure
+	logrus.RegisterExitHandler(printFailureSummary)
+	logrus.RegisterExitHandler(func() {
+		notifyCompletion(notifyStatusFailure)
+	})
+
+	if pluginPattern == "" {
+		runTasks(tasks)
+		printSummary()
+		notifyCompletion(notifyStatusSuccess)
+		return
+	}
+
+	plugins, err := findPlugins(project, pluginPattern)
+	if err != nil {
+		logger.Fatalf("failed to find plugins: %v", err)
+	}
+	if len(plugins) == 0 {
+		logger.Fatalf("no plugins matching '%s'", pluginPattern)
+	}
+
+	pluginLogger := logger
+	var failed int
+	for _, plugin = range plugins {
+		logger = pluginLogger.WithField("plugin", plugin)
+		err = runPluginTasks(tasks)
+		if err != nil {
+			failed++
+		}
+		summary.addPlugin(plugin, err)
+	}
+
+	printSummary()
+	if failed > 0 {
+		logger = pluginLogger
+		logger.Fatalf("%d of %d plugins failed", failed, len(plugins))
+	}
+	notifyCompletion(notifyStatusSuccess)
+}
+
+// runTasks locks the plugin if required and runs the tasks in order
+func runTasks(tasks []string) {
+	// Prevent concurrent runs from overwriting each other's plugin files
+	for _, t := range tasks {
+		if !pluginLockTasks[t] {
+			continue
+		}
+
+		pluginDir, err := getPluginDir(project, plugin)
+		if err != nil {
+			logger.Fatalf("failed to get plugin dir: %v", err)
+		}
+
+		releaseLock, err := acquirePluginLock(pluginDir, lockTimeout)
+		if err != nil {
+			logger.Fatalf("failed to lock the plugin: %v", err)
+		}
+		defer releaseLock()
+		logrus.RegisterExitHandler(releaseLock)
+		break
+	}
+
+	taskLogger := logger
+	for _, task = range tasks {
+		logger = taskLogger.WithField("task", task)
+		runTask(task)
+	}
+	logger = taskLogger
+}
+
+// uploadPackageSource archives the plugin content, uploads the descriptor and the content archive to the api and creates the package jobs
+func uploadPackageSource() {
+	pluginDir, err := getPluginDir(project, plugin)
+	if err != nil {
+		logger.Fatalf("failed to get plugin dir: %v", err)
+	}
+
+	pluginContentTempDir, err := getPluginTempDir(project, plugin)
+	if err != nil {
+		logger.Fatalf("failed to get plugin temp dir: %v", err)
+	}
+
+	cleanStaleTempArchives(archiveRootDir)
+	defer useChecksumCache(pluginDir)()
+
+	err = checkVersionMismatch(project, plugin)
+	if err != nil {
+		if !allowVersionMismatch {
+			logger.Fatalf("failed to check versions: %v", err)
+		}
+		logger.Warningf("version check: %v", err)
+	}
+
+	var packageVersion *semver.Version
+	if versionSource != "" {
+		packageVersion, err = getVersion(project, plugin, versionSource)
+		if err != nil {
+			logger.Fatalf("failed to get the %s version: %v", versionSource, err)
+		}
+		logger.Infof("detected %s version %s", versionSource, packageVersion.String())
+	}
+	if versionOverride != nil {
+		if packageVersion != nil {
+			logger.Infof("overriding the detected version %s with %s", packageVersion.String(), versionOverride.String())
+		}
+		packageVersion = versionOverride
+	}
+	if packageVersion != nil {
+		logger.Infof("uploading version %s", packageVersion.String())
+		summary.Version = packageVersion.String()
+		manifest.Version = packageVersion.String()
+	}
+
+	if engineVersion == "" {
+		engineVersion, err = detectEngineVersion(project, uatPath)
+		if err != nil {
+			logger.Warningf("failed to detect the engine version: %v", err)
+		}
+	}
+	if engineVersion != "" {
+		logger.Infof("built with engine %s", engineVersion)
+		manifest.EngineVersion = engineVersion
+	}
+
+	uploadStartTime := time.Now()
+
+	// The prebuilt package is uploaded as is
+	var releaseArchiveFiles []archiver.File
+	var releaseArchivePaths map[string]string
+	if packagePath == "" {
+		var ok bool
+		releaseArchiveFiles, releaseArchivePaths, ok = prepareContentFiles(pluginDir, pluginContentTempDir)
+		if !ok {
+			return
+		}
+	}
+
+	logger.Debugf("uploading '%s' package descriptor", plugin)
+	upluginName, err := getPluginDescriptorPath(pluginDir, plugin)
+	if err != nil {
+		logger.Fatalf("failed to find plugin descriptor: %v", err)
+	}
+	upluginOriginalPath := filepath.Base(upluginName)
+
+	existingFiles, err := getEntityFiles(entityId)
+	if err != nil {
+		withErrorFields(err).Fatalf("failed to get existing entity files: %v", err)
+	}
+
+	// The descriptor is shared by the platforms, keep the one uploaded with the release
+	keepDescriptor := skipDescriptor || (appendToRelease && hasFileOfType(existingFiles, "uplugin"))
+	if skipDescriptor && !hasFileOfType(existingFiles, "uplugin") {
+		logger.Fatalf("can't skip the descriptor upload, the entity has no descriptor yet")
+	}
+
+	var descriptorVersion int
+	if !keepDescriptor {
+		descriptorVersion, err = nextFileVersion(existingFiles, "uplugin", platform, deployment, force)
+		if err != nil {
+			logger.Fatalf("failed to upload entity file: %v", err)
+		}
+	}
+
+	// Incremental content is merged into the existing content by the server
+	var contentVersion int
+	if !incremental {
+		contentVersion, err = nextFileVersion(existingFiles, "uplugin_content", platform, deployment, force)
+		if err != nil {
+			logger.Fatalf("failed to upload entity file: %v", err)
+		}
+	}
+
+	var stats retryStats
+	if keepDescriptor {
+		logger.Infof("entity already has a descriptor, uploading the content only")
+	} else {
+		stats, err = withRetry("descriptor upload", func() error {
+			descriptorMetadata := FileMetadata{Type: "uplugin", OriginalPath: upluginOriginalPath, Version: descriptorVersion, Platform: platform, Deployment: deployment}
+			return uploadEntityFile(entityId, "uplugin", "application/json", upluginName, upluginOriginalPath, nil, mergeParams(fileMetadataParams(descriptorMetadata), formParams), defaultFileFieldName)
+		})
+		if err != nil {
+			withErrorFields(err).Fatalf("failed to upload entity file: %v", err)
+		}
+		summary.addFile("uplugin", upluginName, descriptorVersion, stats)
+	}
+
+	manifest.EntityId = entityId
+	manifest.Commit = sourceRevision.Commit
+	manifest.Branch = sourceRevision.Branch
+	if manifestPath != "" {
+		err = manifest.addFile(upluginName, "uplugin", "application/json", upluginOriginalPath, nil)
+		if err != nil {
+			logger.Fatalf("failed to add descriptor to the manifest: %v", err)
+		}
+	}
+
+	logger.Debugf("compressing '%s' package content", plugin)
+	archiveDir, err := os.MkdirTemp(archiveParentDir(archiveRootDir), tempArchiveDirPattern)
+	if err != nil {
+		logger.Fatalf("failed to create a temp archive dir: %v", err)
+	}
+
+	// Delete the zip file after upload or on failure
+	var zip *os.File
+	cleanupArchive := func() {
+		removeTempArchive(zip, archiveDir)
+	}
+	defer cleanupArchive()
+	logrus.RegisterExitHandler(cleanupArchive)
+
+	zipName := filepath.Join(archiveDir, plugin+".zip")
+	var zipSize int64
+	if packagePath != "" {
+		zipName, zipSize, err = preparePackageArchive(packagePath, zipName)
+		if err != nil {
+			logger.Fatalf("invalid package archive: %v", err)
+		}
+		logger.Infof("uploading the %d bytes package archive %s", zipSize, zipName)
+	} else {
+		zip, err = os.Create(zipName)
+		if err != nil {
+			logger.Fatalf("failed to create a zip file: %v", err)
+		}
+
+		endArchivePhase := startPhase(phaseArchive)
+		if reproducible {
+			sortArchiveFiles(releaseArchiveFiles)
+		}
+		archiveManifest, err := newArchiveManifest(releaseArchiveFiles, releaseArchivePaths)
+		if err != nil {
+			logger.Fatalf("failed to create the archive manifest: %v", err)
+		}
+
+		contentSize := archiveContentSize(releaseArchiveFiles)
+		progress := &archiveProgress{total: contentSize}
+		err = archiveZip(context.Background(), zip, releaseArchiveFiles, compressionLevel, reproducible, archiveManifest, progress)
+		if err != nil {
+			logger.Fatalf("failed to zip release archive files: %v", err)
+		}
+
+		fi, err := zip.Stat()
+		if err != nil {
+			logger.Fatalf("failed to get zip file info: %v", err)
+		}
+		zipSize = fi.Size()
+
+		if contentSize > 0 {
+			logger.Infof("archived %d files (%d deflated, %d stored), %d bytes of content to %d bytes, ratio: %.3f", progress.files, progress.files-progress.stored, progress.stored, contentSize, zipSize, float64(zipSize)/float64(contentSize))
+		} else {
+			logger.Infof("archived %d files to %d bytes", progress.files, zipSize)
+		}
+		endArchivePhase()
+	}
+
+	err = checkMaxSize("archive", zipSize)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	logger.Debugf("uploading '%s' package content", plugin)
+
+	params := uploadUrlParams(packageVersion)
+	if autoChunk {
+		// The single request uploads only use the chunk as the read buffer, the multipart uploads get the part urls for the chunk size unless -partSize is set
+		readBufferSize = autoChunkSize(zipSize, false)
+		if partSize == 0 {
+			params["part-size"] = strconv.FormatInt(autoChunkSize(zipSize, true), 10)
+		}
+		logger.Infof("using %d bytes read buffer and %s bytes parts for %d bytes content", readBufferSize, params["part-size"], zipSize)
+	}
+	if incremental {
+		params["incremental"] = "true"
+	}
+	// The release version is sent with the upload url params, the incremental content has no file version of its own
+	if contentVersion > 0 {
+		params["version"] = strconv.Itoa(contentVersion)
+	}
+	// The presigned url is requested for the type the content is uploaded with
+	contentType, ok := overrideContentType(zipName)
+	if !ok {
+		contentType = "application/zip"
+	}
+	contentMetadata, stats, err := uploadPresignedFile(entityId, "uplugin_content", contentType, zipName, plugin+".zip", zipSize, params)
+	if err != nil {
+		withErrorFields(err).Fatalf("failed to upload: %v", err)
+	}
+	summary.addFile("uplugin_content", zipName, contentVersion, stats)
+
+	// The release notes are versioned along with the package
+	var releaseNotesMetadata FileMetadata
+	if releaseNotesPath != "" {
+		releaseNotesMetadata, stats, err = uploadStandaloneFile(entityId, releaseNotesPath, fileTypeReleaseNotes, "", filepath.Base(releaseNotesPath), packageVersion)
+		if err != nil {
+			withErrorFields(err).Fatalf("failed to upload release notes: %v", err)
+		}
+		summary.addFile(fileTypeReleaseNotes, releaseNotesPath, releaseNotesMetadata.Version, stats)
+		if releaseNotesMetadata.Id == nil {
+			logger.Warningf("no release notes file id returned, the package jobs won't reference the release notes")
+		}
+	}
+
+	if manifestPath != "" {
+		err = manifest.addFile(zipName, "uplugin_content", contentType, plugin+".zip", contentMetadata)
+		if err != nil {
+			logger.Fatalf("failed to add content to the manifest: %v", err)
+		}
 
-			fi, err := zip.Stat()
+		if releaseNotesPath != "" {
+			// The mime detected on upload is taken from the metadata
+			err = manifest.addFile(releaseNotesPath, fileTypeReleaseNotes, "", filepath.Base(releaseNotesPath), &releaseNotesMetadata)
 			if err != nil {
-				logrus.Fatalf("failed to get zip file info: %v", err)
+				logger.Fatalf("failed to add release notes to the manifest: %v", err)
 			}
-			zipSize := fi.Size()
+		}
+
+		err = writeManifest(manifestPath, manifest)
+		if err != nil {
+			logger.Fatalf("failed to write manifest: %v", err)
+		}
+	}
 
-			logrus.Debugf("uploading '%s' package content", plugin)
+	// The next incremental upload starts from this one and the temp content is removed once its jobs succeed,
+	// the content of a failed run is kept to upload it again
+	completeUpload := func() {
+		applyReleaseVisibility(entityId, releaseMetadata.Public)
 
-			//err = uploadEntityFile(entityId, "uplugin_content", "application/zip", zipName, plugin+".zip", nil)
-			var presignedFileMetadata FileMetadata
-			presignedFileMetadata, err = getEntityFileUploadUrl(entityId, "uplugin_content", "application/zip", zipSize, plugin+".zip")
+		// The prebuilt package may not match the plugin content, so the next incremental upload can't start from it
+		if packagePath != "" {
+			return
+		}
+		err := writeLastUploadTime(pluginDir, uploadStartTime)
+		if err != nil {
+			logger.Warningf("failed to record the upload time: %v", err)
+		}
+
+		if cleanTempContent {
+			err = cleanTempContentDir(pluginContentTempDir, dryRun)
 			if err != nil {
-				logrus.Fatalf("failed to get presigned upload file metadata: %v", err)
+				logger.Warningf("failed to clean the temp content: %v", err)
 			}
+		}
+	}
+
+	// Report the complete release file set including the files uploaded from the other machines
+	if appendToRelease {
+		files, err := getEntityFiles(entityId)
+		if err != nil {
+			withErrorFields(err).Warningf("failed to get the release files: %v", err)
+		} else {
+			summary.addEntityFiles(files)
+		}
+	}
+
+	if noJob {
+		logger.Infof("skipping package job creation")
+		completeUpload()
+		return
+	}
+
+	endJobCreatePhase := startPhase(phaseJobCreate)
+	var jobs []JobMetadata
+
+	// Attach to the jobs left by a previous run if requested, they were created for the content uploaded by that run
+	if attachJob {
+		jobs, err = findUnfinishedPackageJobs(entityId)
+		if err != nil {
+			withErrorFields(err).Warningf("failed to get the existing package jobs, creating new ones: %v", err)
+			jobs = nil
+		}
+		for _, job := range jobs {
+			logger.Infof("attaching to the %s package job %s (%s)", job.Status, job.Id.String(), job.Platform)
+		}
+	}
+
+	if len(jobs) == 0 {
+		var idempotencyKey uuid.UUID
+		idempotencyKey, err = uuid.NewV4()
+		if err != nil {
+			logger.Fatalf("failed to generate the idempotency key: %v", err)
+		}
+		_, err = withRetryIf("package job creation", retryNotProcessed, func() error {
+			var err error
+			jobs, err = createPackageJobs(entityId, packageVersion, releaseMetadata, sourceRevision, engineVersion, releaseNotesMetadata.Id, idempotencyKey.String())
+			return err
+		})
+	}
+	endJobCreatePhase()
+	if err == nil && len(jobs) == 0 {
+		err = fmt.Errorf("no package jobs created for entity %s", entityId.String())
+	}
+	if err != nil {
+		withErrorFields(err).Fatalf("failed to create package jobs: %v", err)
+	}
+
+	if wait {
+		jobs, err = waitForJobs(jobs, pollInterval, waitTimeout)
+		if err != nil {
+			summary.addJobs(jobs)
+			withErrorFields(err).Fatalf("failed to wait for package jobs: %v", err)
+		}
+	}
+	summary.addJobs(jobs)
+	completeUpload()
+}
 
-			logrus.Debugf("uploading file %s", presignedFileMetadata.Id.String())
+// prepareContentFiles enumerates the plugin content files to archive and checks them against the limits, returns false if there is nothing to upload
+func prepareContentFiles(pluginDir string, pluginContentTempDir string) ([]archiver.File, map[string]string, bool) {
+	releaseArchiveFiles, releaseArchivePaths, err := collectContentFiles(pluginDir, pluginContentTempDir)
+	if err != nil {
+		logger.Fatalf("failed to enumerate release archive files to zip: %v", err)
+	}
 
-			//params := map[string]string{
-			//	"version":      strconv.FormatInt(int64(presignedFileMetadata.Version), 10),
-			//	"index":        strconv.FormatInt(int64(presignedFileMetadata.Index), 10),
-			//	"type":         presignedFileMetadata.Type,
-			//	"originalPath": presignedFileMetadata.OriginalPath,
-			//}
+	// An empty archive would replace the release content with nothing
+	fileCount := archiveFileCount(releaseArchiveFiles)
+	if fileCount == 0 {
+		if !allowEmpty {
+			logger.Fatalf("no files to archive in %s, check the content dirs or pass -allowEmpty to upload an empty archive", pluginContentTempDir)
+		}
+		logger.Warningf("uploading an empty archive")
+	}
+	totalSize := archiveContentSize(releaseArchiveFiles)
+	logger.Infof("archiving %d files, %d bytes of content", fileCount, totalSize)
+	err = checkMaxSize("content", totalSize)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	err = checkMaxFiles(fileCount)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
 
-			err = uploadEntityFileToS3(presignedFileMetadata.Url, entityId, zipName)
+	if incremental {
+		if since.IsZero() {
+			since, err = readLastUploadTime(pluginDir)
 			if err != nil {
-				logrus.Fatalf("failed to upload: %v", err)
+				logger.Fatalf("failed to get the incremental upload start time: %v", err)
 			}
+			if since.IsZero() {
+				logger.Fatalf("no previous upload recorded, pass -since or run a full upload first")
+			}
+		}
 
-			err = createPackageJobs(entityId)
+		releaseArchiveFiles = filterModifiedFiles(releaseArchiveFiles, since)
+		if len(releaseArchiveFiles) == 0 {
+			logger.Infof("no content modified since %s", since.Format(time.RFC3339))
+			return nil, nil, false
 		}
+		logger.Infof("archiving %d entries modified since %s", len(releaseArchiveFiles), since.Format(time.RFC3339))
+	}
+
+	// Fail before uploading anything if the archive can't fit on the disk
+	err = checkFreeSpace(archiveParentDir(archiveRootDir), archiveContentSize(releaseArchiveFiles))
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	return releaseArchiveFiles, releaseArchivePaths, true
+}
+
+// runTask runs a single task, terminating on failure
+func runTask(task string) {
+	switch task {
+	case taskUploadPackageSource:
+		uploadPackageSource()
 	case taskUnzipPackageSource:
 		{
-			logrus.Debugf("unzip '%s' package content", plugin)
+			pluginDir, err := getPluginDir(project, plugin)
+			if err != nil {
+				logger.Fatalf("failed to get plugin dir: %v", err)
+			}
+
+			extractRoot, err := resolveExtractDir(pluginDir, extractDir, allowOutsidePlugin)
+			if err != nil {
+				logger.Fatalf("invalid extract dir: %v", err)
+			}
+			defer useChecksumCache(pluginDir)()
+
+			err = prepareExtractDir(extractRoot)
+			if err != nil {
+				logger.Fatalf("invalid extract dir: %v", err)
+			}
+
+			logger.Debugf("unzip '%s' package content to %s", plugin, extractRoot)
 			zipName := filepath.Join(pluginDir, "temp", plugin+".zip")
 			zip, err := os.Open(zipName)
 			if err != nil {
-				logrus.Fatalf("failed to open a zip file: %v", err)
+				logger.Fatalf("failed to open a zip file: %v", err)
 			}
 			defer func(zip *os.File) {
 				err := zip.Close()
 				if err != nil {
-					logrus.Errorf("failed to close a zip file: %v", err)
+					logger.Errorf("failed to close a zip file: %v", err)
 				}
 			}(zip)
 
@@ -823,7 +2488,44 @@ func main() {
 				Archival: archiver.Zip{},
 			}
 
+			var extracted, skipped, matched, excluded int
+			var archiveManifest *ArchiveManifest
+			var expectedEntries []extractedEntry
 			handler := func(ctx context.Context, f archiver.File) error {
+				// The manifest is used to validate the extracted files and is not extracted itself
+				if f.NameInArchive == archiveManifestName {
+					rc, err := f.Open()
+					if err != nil {
+						return err
+					}
+					defer rc.Close()
+
+					m, err := readArchiveManifest(rc)
+					if err != nil {
+						return err
+					}
+					archiveManifest = &m
+					return nil
+				}
+
+				if !matchesIncludePatterns(f.NameInArchive, includeEntries) {
+					excluded++
+					return nil
+				}
+				matched++
+
+				dest, err := extractPath(extractRoot, f.NameInArchive)
+				if err != nil {
+					return err
+				}
+				expectedEntries = append(expectedEntries, extractedEntry{name: f.NameInArchive, dest: dest, size: f.Size(), isDir: f.IsDir()})
+
+				if resume && !f.IsDir() && isExtracted(f, dest) {
+					logger.Debugf("skipping already extracted '%s'", f.NameInArchive)
+					skipped++
+					return nil
+				}
+
 				rc, err := f.Open()
 				if err != nil {
 					return err
@@ -831,55 +2533,243 @@ func main() {
 				defer rc.Close()
 
 				if f.IsDir() {
-					err = os.MkdirAll(filepath.Join(pluginDir, "Content", f.NameInArchive), f.Mode())
+					err = os.MkdirAll(dest, f.Mode())
 					if err == nil || os.IsExist(err) {
 						return nil
 					}
 					return err
 				}
 
-				out, err := os.OpenFile(filepath.Join(pluginDir, "Content", f.NameInArchive), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+				// The parent dir entry may be excluded
+				if len(includeEntries) > 0 {
+					err = os.MkdirAll(filepath.Dir(dest), 0755)
+					if err != nil {
+						return err
+					}
+				}
+
+				out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
 				if err != nil {
 					return err
 				}
+				extracted++
 				_, err = io.Copy(out, rc)
+				if closeErr := out.Close(); err == nil {
+					err = closeErr
+				}
 				return err
 			}
 
 			err = format.Extract(context.Background(), zip, nil, handler)
 			if err != nil {
-				logrus.Fatalf("failed to unzip release archive files: %v", err)
+				logger.Fatalf("failed to unzip release archive files: %v", err)
+			}
+
+			if resume {
+				logger.Infof("extracted %d files, skipped %d already extracted files", extracted, skipped)
+			}
+
+			// Make sure every archive entry was written, the dirs are expected as dirs
+			var dirs int
+			for _, entry := range expectedEntries {
+				if entry.isDir {
+					dirs++
+				}
+			}
+			missing := findMissingEntries(expectedEntries)
+			if len(missing) > 0 {
+				for _, entry := range missing {
+					logger.Errorf("archive entry '%s' (%d bytes) was not extracted to %s", entry.name, entry.size, entry.dest)
+				}
+				logger.Fatalf("%d of %d archive entries were not extracted", len(missing), len(expectedEntries))
+			}
+			logger.Infof("verified %d extracted files and %d dirs", len(expectedEntries)-dirs, dirs)
+			if len(includeEntries) > 0 {
+				logger.Infof("%d entries matched the include patterns, skipped %d not matching entries", matched, excluded)
+				if archiveManifest != nil {
+					filtered := filterArchiveManifest(*archiveManifest, includeEntries)
+					archiveManifest = &filtered
+				}
+			}
+
+			if archiveManifest == nil {
+				logger.Warningf("no manifest in the archive, skipping extracted files validation")
+			} else {
+				invalid := validateExtractedFiles(*archiveManifest, extractRoot)
+				if invalid > 0 {
+					logger.Fatalf("%d of %d extracted files failed validation", invalid, len(archiveManifest.Files))
+				}
+				logger.Infof("validated %d extracted files", len(archiveManifest.Files))
+			}
+		}
+	case taskPackagePlugin:
+		{
+			pluginDir, err := getPluginDir(project, plugin)
+			if err != nil {
+				logger.Fatalf("failed to get plugin dir: %v", err)
+			}
+
+			pluginContentTempDir, err := getPluginTempDir(project, plugin)
+			if err != nil {
+				logger.Fatalf("failed to get plugin temp dir: %v", err)
+			}
+
+			path := uatPath
+			if path == "" {
+				path, err = findUATPath(project)
+				if err != nil {
+					logger.Fatalf("failed to find UAT: %v", err)
+				}
+			}
+
+			upluginName, err := getPluginDescriptorPath(pluginDir, plugin)
+			if err != nil {
+				logger.Fatalf("failed to find plugin descriptor: %v", err)
+			}
+
+			err = packagePlugin(path, upluginName, pluginContentTempDir, platform)
+			if err != nil {
+				logger.Fatalf("failed to package plugin: %v", err)
+			}
+		}
+	case taskDownload:
+		{
+			if destDir == "" {
+				logger.Fatalf("no destination dir to download the files to")
+			}
+
+			err := downloadEntityFiles(entityId, fileName, destDir)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to download files: %v", err)
+			}
+		}
+	case taskListFiles:
+		{
+			files, err := getEntityFiles(entityId)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to get entity files: %v", err)
+			}
+
+			if len(files) == 0 {
+				logger.Infof("no files")
+			}
+			summary.addEntityFiles(files)
+		}
+	case taskDeleteFile:
+		{
+			err := deleteEntityFiles(entityId, fileId, fileType, yes)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to delete files: %v", err)
+			}
+		}
+	case taskDoctor:
+		{
+			summary.Checks = runDoctor()
+		}
+	case taskManifest:
+		{
+			pluginDir, err := getPluginDir(project, plugin)
+			if err != nil {
+				logger.Fatalf("failed to get plugin dir: %v", err)
+			}
+
+			pluginContentTempDir, err := getPluginTempDir(project, plugin)
+			if err != nil {
+				logger.Fatalf("failed to get plugin temp dir: %v", err)
+			}
+			defer useChecksumCache(pluginDir)()
+
+			err = writeContentManifest(pluginDir, pluginContentTempDir, manifestPath)
+			if err != nil {
+				logger.Fatalf("failed to write the content manifest: %v", err)
+			}
+		}
+	case taskUploadFile:
+		{
+			if filePath == "" || fileType == "" {
+				logger.Fatalf("no -filePath or -type of the file to upload")
+			}
+
+			metadata, stats, err := uploadStandaloneFile(entityId, filePath, fileType, fileMime, originalPath, versionOverride)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to upload file: %v", err)
+			}
+			summary.addFile(fileType, filePath, metadata.Version, stats)
+		}
+	case taskPruneReleases:
+		{
+			if appId.IsNil() {
+				logger.Fatalf("no -appId of the releases to prune")
+			}
+
+			err := pruneReleases(appId, keep, keepConstraint, yes)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to prune releases: %v", err)
+			}
+		}
+	case taskAbortMultipart:
+		{
+			err := abortMultipartUploads(entityId, multipartUploadId, dryRun, yes)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to abort multipart uploads: %v", err)
+			}
+		}
+	case taskLogin:
+		{
+			err := login(authUrl, clientId)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to log in: %v", err)
+			}
+		}
+	case taskClean:
+		{
+			err := cleanPluginArtifacts(project, plugin, cleanExtracted, dryRun, yes)
+			if err != nil {
+				logger.Fatalf("failed to clean: %v", err)
+			}
+		}
+	case taskVerifyRelease:
+		{
+			if manifestPath == "" {
+				logger.Fatalf("no manifest to verify the release against")
+			}
+
+			err := verifyRelease(entityId, manifestPath)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to verify release: %v", err)
+			}
+		}
+	case taskCheckUpdate:
+		{
+			if appId.IsNil() {
+				logger.Fatalf("no -appId of the SDK releases")
+			}
+			if platform == "" {
+				logger.Fatalf("no -platform to check the updates for")
+			}
+
+			// Get the current version of the SDK from the configured source, the INI file by default
+			source := versionSource
+			if source == "" {
+				source = versionSourceProject
+			}
+			currentVersion, err := getVersion(project, plugin, source)
+			if err != nil {
+				logger.Fatalf("failed to get the current version: %v", err)
+			}
+
+			// Check the latest version of every target platform against the current one and the update constraint
+			summary.Updates, err = checkUpdates(currentVersion, strings.Split(platform, ","), updateConstraint)
+			if err != nil {
+				withErrorFields(err).Fatalf("failed to check the updates: %v", err)
 			}
 		}
 	//case taskUpdateSDK:
 	//	{
-	//		// Get current version of the SDK from the INI file.
-	//		currentVersion, err := getProjectVersion(project)
-	//		if err != nil {
-	//			logrus.Fatalf("failed to get the current version: %v", err)
-	//		}
-	//		if currentVersion == nil {
-	//			logrus.Fatalf("failed to get the current version")
-	//		}
-	//
-	//		// Get the latest version from the API.
-	//		latestVersion, err := getLatestVersion()
-	//		if err != nil {
-	//			logrus.Fatalf("failed to get the latest version: %v", err)
-	//		}
-	//		if latestVersion == nil {
-	//			logrus.Fatalf("failed to get the latest version")
-	//		}
-	//
-	//		// Check if the latest version greater than the current.
-	//		if !currentVersion.LessThan(latestVersion) {
-	//			logrus.Debugf("up to date")
-	//			os.Exit(0)
-	//		}
-	//
-	//		// 4. Download files.
-	//		// 5. Replace files.
-	//		// 6. Restart editor.
+	//		// 1. Check the update of every target platform, see the checkUpdate task.
+	//		// 2. Download files.
+	//		// 3. Replace files.
+	//		// 4. Restart editor.
 	//	}
 	default:
 		flag.Usage()
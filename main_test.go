@@ -0,0 +1,740 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockApi serves the endpoints of the package upload and records what was sent to them
+type mockApi struct {
+	server *httptest.Server
+
+	mu             sync.Mutex
+	descriptor     []byte     // uploaded descriptor
+	uploadUrlQuery url.Values // query of the content upload url request
+	content        []byte     // content uploaded to the presigned url
+	contentType    string     // content type of the upload to the presigned url
+	jobRequests    []map[string]interface{}
+	entityUpdates  []EntityMetadata
+
+	jobStatus int // status code of the job creation, 200 if 0
+}
+
+func newMockApi(t *testing.T) *mockApi {
+	api := &mockApi{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/entities/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET":
+			fmt.Fprint(w, `{"data":{"files":[]}}`)
+		case r.Method == "PATCH":
+			var entity EntityMetadata
+			_ = json.NewDecoder(r.Body).Decode(&entity)
+			api.mu.Lock()
+			api.entityUpdates = append(api.entityUpdates, entity)
+			api.mu.Unlock()
+			b, _ := json.Marshal(EntityMetadataContainer{EntityMetadata: entity})
+			_, _ = w.Write(b)
+		case r.Method == "PUT" && strings.HasSuffix(r.URL.Path, "/files/upload"):
+			file, _, err := r.FormFile(defaultFileFieldName)
+			if err != nil {
+				t.Errorf("failed to read the uploaded descriptor: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			b, _ := io.ReadAll(file)
+			api.mu.Lock()
+			api.descriptor = b
+			api.mu.Unlock()
+			fmt.Fprint(w, `{"data":{}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/files/upload", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		api.uploadUrlQuery = r.URL.Query()
+		api.mu.Unlock()
+		fmt.Fprintf(w, `{"data":{"id":%q,"type":%q,"url":%q}}`, uuid.Must(uuid.NewV4()).String(), r.URL.Query().Get("type"), api.server.URL+"/storage/"+r.URL.Query().Get("original-path"))
+	})
+
+	mux.HandleFunc("/storage/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			t.Errorf("unexpected storage request %s %s", r.Method, r.URL.Path)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		api.mu.Lock()
+		api.content = b
+		api.contentType = r.Header.Get("Content-Type")
+		api.mu.Unlock()
+		sum := md5.Sum(b)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	})
+
+	mux.HandleFunc("/jobs/package", func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&m)
+		api.mu.Lock()
+		api.jobRequests = append(api.jobRequests, m)
+		status := api.jobStatus
+		api.mu.Unlock()
+		if status >= 400 {
+			http.Error(w, `{"message":"job creation failed"}`, status)
+			return
+		}
+		fmt.Fprintf(w, `{"data":[{"id":%q,"status":"pending","platform":%q}]}`, uuid.Must(uuid.NewV4()).String(), platform)
+	})
+
+	api.server = httptest.NewServer(mux)
+	t.Cleanup(api.server.Close)
+	return api
+}
+
+// setupUploadFixture creates a project with the packaged plugin content in a temp dir, runs the test from the project dir against the api and
+// returns the content files keyed by their archive names
+func setupUploadFixture(t *testing.T, api *mockApi) map[string]string {
+	projectDir := t.TempDir()
+	files := map[string]string{
+		"Proj.uproject":                                    `{"EngineAssociation":"5.1"}`,
+		"Config/DefaultGame.ini":                           "[/Script/EngineSettings.GeneralProjectSettings]\nProjectVersion=1.2.0\n",
+		"Plugins/Plug/Plug.uplugin":                        `{"VersionName":"1.2.0"}`,
+		"Plugins/Plug/Temp/Plug/Content/Maps/Level.umap":   "level",
+		"Plugins/Plug/Temp/Plug/Content/Textures/A.uasset": strings.Repeat("texture", 1000),
+		"Plugins/Plug/Temp/Plug/Content/Textures/B.uasset": "",
+	}
+	for name, content := range files {
+		p := filepath.Join(projectDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	// The globals are set by main from the flags
+	apiUrl = api.server.URL
+	token = "token"
+	project = "Proj"
+	plugin = "Plug"
+	entityId = uuid.Must(uuid.NewV4())
+	platform = "Win64"
+	engineVersion = "5.1.0"
+	versionSource = ""
+	versionOverride = nil
+	incremental = false
+	since = time.Time{}
+	appendToRelease = false
+	cleanTempContent = false
+	autoChunk = false
+	releaseMetadata = ReleaseMetadata{}
+	mimeOverrides = nil
+	partSize = 0
+	archiveRootDir = t.TempDir()
+	compressionLevel = flate.DefaultCompression
+	symlinkMode = symlinksSkip
+	readBufferSize = minChunkSize
+	readBuffers = 2
+	partConcurrency = 1
+	hashConcurrency = 1
+	retries = 0
+	httpClient = &http.Client{}
+	progressFunc = func(ProgressEvent) {}
+	summary = Summary{}
+	summaryPrinted = false
+	manifest = Manifest{}
+	t.Cleanup(func() { progressFunc = logProgressEvent })
+
+	content := map[string]string{}
+	prefix := "Plugins/Plug/Temp/Plug/"
+	for name, c := range files {
+		if strings.HasPrefix(name, prefix) {
+			content[strings.TrimPrefix(name, prefix)] = c
+		}
+	}
+	return content
+}
+
+// readZipEntries returns the file entries of the zip archive keyed by name
+func readZipEntries(t *testing.T, b []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("uploaded content is not a zip archive: %v", err)
+	}
+
+	entries := map[string]string{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[f.Name] = string(c)
+	}
+	return entries
+}
+
+func TestUploadPackageSource(t *testing.T) {
+	api := newMockApi(t)
+	content := setupUploadFixture(t, api)
+
+	uploadPackageSource()
+
+	if string(api.descriptor) != `{"VersionName":"1.2.0"}` {
+		t.Errorf("uploaded descriptor %q", api.descriptor)
+	}
+
+	q := api.uploadUrlQuery
+	if q.Get("entityId") != entityId.String() || q.Get("type") != "uplugin_content" || q.Get("platform") != "Win64" {
+		t.Errorf("unexpected upload url query %v", q)
+	}
+	if q.Get("size") != fmt.Sprint(len(api.content)) {
+		t.Errorf("upload url requested for %s bytes, uploaded %d bytes", q.Get("size"), len(api.content))
+	}
+
+	entries := readZipEntries(t, api.content)
+	for name, c := range content {
+		got, ok := entries[name]
+		if !ok {
+			t.Errorf("archive entry %s is missing", name)
+		} else if got != c {
+			t.Errorf("archive entry %s has %d bytes, want %d", name, len(got), len(c))
+		}
+	}
+
+	if len(api.jobRequests) != 1 || api.jobRequests[0]["entityId"] != entityId.String() {
+		t.Fatalf("unexpected job requests %v", api.jobRequests)
+	}
+	if len(summary.Jobs) != 1 || summary.Jobs[0].Status != "pending" {
+		t.Errorf("unexpected summary jobs %+v", summary.Jobs)
+	}
+	if _, err := os.Stat(filepath.Join("Plugins", "Plug", lastUploadFileName)); err != nil {
+		t.Errorf("upload time not recorded: %v", err)
+	}
+}
+
+func TestUploadPackageSourceVersion(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	versionSource = versionSourcePlugin
+
+	uploadPackageSource()
+
+	if v := api.uploadUrlQuery.Get("release-version"); v != "1.2.0" {
+		t.Errorf("upload url release version %q, want 1.2.0", v)
+	}
+	if len(api.jobRequests) != 1 || api.jobRequests[0]["version"] != "1.2.0" {
+		t.Errorf("unexpected job requests %v", api.jobRequests)
+	}
+}
+
+func TestUploadPackageSourceVersionOverride(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	versionSource = versionSourcePlugin
+	versionOverride = semver.MustParse("1.2.0+build.7")
+
+	uploadPackageSource()
+
+	if v := api.uploadUrlQuery.Get("release-version"); v != "1.2.0+build.7" {
+		t.Errorf("upload url release version %q, want 1.2.0+build.7", v)
+	}
+	if len(api.jobRequests) != 1 || api.jobRequests[0]["version"] != "1.2.0+build.7" {
+		t.Errorf("unexpected job requests %v", api.jobRequests)
+	}
+	if summary.Version != "1.2.0+build.7" {
+		t.Errorf("summary version %q, want 1.2.0+build.7", summary.Version)
+	}
+}
+
+func TestUploadPackageSourceAppendIncremental(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	versionSource = versionSourcePlugin
+	appendToRelease = true
+	incremental = true
+	since = time.Now().Add(-time.Hour)
+
+	uploadPackageSource()
+
+	q := api.uploadUrlQuery
+	if q.Get("release-version") != "1.2.0" || q.Get("incremental") != "true" {
+		t.Errorf("unexpected upload url query %v", q)
+	}
+	if _, ok := q["version"]; ok {
+		t.Errorf("incremental content sent with the file version %q", q.Get("version"))
+	}
+}
+
+func TestUploadStandaloneFileContentType(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("release notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, _, err := uploadStandaloneFile(entityId, path, fileTypeReleaseNotes, "", "", nil)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if metadata.Id == nil {
+		t.Errorf("no file id returned")
+	}
+
+	mime := api.uploadUrlQuery.Get("mime")
+	if !strings.HasPrefix(mime, "text/plain") {
+		t.Errorf("upload url requested for %q, want text/plain", mime)
+	}
+	if api.contentType != mime {
+		t.Errorf("uploaded with content type %q, upload url requested for %q", api.contentType, mime)
+	}
+	if string(api.content) != "release notes" {
+		t.Errorf("uploaded %q", api.content)
+	}
+}
+
+func TestUploadPackageSourceJobFailure(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	api.jobStatus = http.StatusInternalServerError
+	cleanTempContent = true
+
+	var out bytes.Buffer
+	summaryOutput = &out
+	output = outputJson
+	t.Cleanup(func() {
+		summaryOutput = os.Stdout
+		output = ""
+	})
+
+	// The plugin tasks report the failure instead of exiting
+	err := runPluginTasks([]string{taskUploadPackageSource})
+	if err == nil || !strings.Contains(err.Error(), "failed to create package jobs") {
+		t.Fatalf("expected the job creation failure, got %v", err)
+	}
+	if len(api.content) == 0 {
+		t.Errorf("content not uploaded before the job creation")
+	}
+	if _, err := os.Stat(filepath.Join("Plugins", "Plug", lastUploadFileName)); !os.IsNotExist(err) {
+		t.Errorf("upload time recorded for the failed run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("Plugins", "Plug", "Temp", "Plug", "Content")); err != nil {
+		t.Errorf("temp content of the failed run removed: %v", err)
+	}
+
+	// The summary is printed once by the run, not by the failed task
+	printSummary()
+	printFailureSummary()
+	if n := strings.Count(out.String(), "\n"); n != 1 {
+		t.Errorf("printed %d summaries: %s", n, out.String())
+	}
+}
+
+func TestUploadPackageSourceCleanTempContent(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	cleanTempContent = true
+
+	uploadPackageSource()
+
+	if len(api.jobRequests) != 1 {
+		t.Fatalf("unexpected job requests %v", api.jobRequests)
+	}
+	if _, err := os.Stat(filepath.Join("Plugins", "Plug", "Temp", "Plug")); !os.IsNotExist(err) {
+		t.Errorf("temp content not removed after the jobs were created: %v", err)
+	}
+}
+
+func TestUploadEntityFileContentMD5(t *testing.T) {
+	var body []byte
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header.Get("Content-MD5")
+	}))
+	defer server.Close()
+	apiUrl = server.URL
+	httpClient = &http.Client{}
+	readBufferSize = minChunkSize
+	readBuffers = 1
+	sendContentMD5 = true
+	t.Cleanup(func() { sendContentMD5 = false })
+
+	path := filepath.Join(t.TempDir(), "Plug.uplugin")
+	if err := os.WriteFile(path, []byte(`{"VersionName":"1.2.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := uploadEntityFile(uuid.Must(uuid.NewV4()), "uplugin", "application/json", path, "Plug.uplugin", nil, map[string]string{"version": "1"}, defaultFileFieldName)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	sum := md5.Sum(body)
+	if want := base64.StdEncoding.EncodeToString(sum[:]); header != want {
+		t.Errorf("Content-MD5 %s does not match the MD5 of the sent body %s", header, want)
+	}
+}
+
+func TestUploadPackageSourceAutoChunk(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	autoChunk = true
+
+	uploadPackageSource()
+
+	want := fmt.Sprint(autoChunkSize(int64(len(api.content)), true))
+	if got := api.uploadUrlQuery.Get("part-size"); got != want {
+		t.Errorf("upload url requested for %q bytes parts, want %s", got, want)
+	}
+}
+
+func TestUploadUrlParamsPartSize(t *testing.T) {
+	platform = ""
+	deployment = ""
+	partSize = 0
+	if _, ok := uploadUrlParams(nil)["part-size"]; ok {
+		t.Errorf("part size requested without -partSize")
+	}
+
+	partSize = 8 * minChunkSize
+	t.Cleanup(func() { partSize = 0 })
+	if got := uploadUrlParams(nil)["part-size"]; got != fmt.Sprint(8*minChunkSize) {
+		t.Errorf("requested part size %q, want %d", got, 8*minChunkSize)
+	}
+}
+
+func TestUploadPackageSourceVisibility(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	public := true
+	releaseMetadata.Public = &public
+
+	uploadPackageSource()
+
+	if len(api.jobRequests) != 1 {
+		t.Fatalf("unexpected job requests %v", api.jobRequests)
+	}
+	if _, ok := api.jobRequests[0]["public"]; ok {
+		t.Errorf("visibility sent with the job request %v", api.jobRequests[0])
+	}
+	if len(api.entityUpdates) != 1 || api.entityUpdates[0].Public == nil || !*api.entityUpdates[0].Public {
+		t.Errorf("unexpected entity updates %+v", api.entityUpdates)
+	}
+	if summary.Public == nil || !*summary.Public {
+		t.Errorf("summary visibility %v, want public", summary.Public)
+	}
+}
+
+func TestUploadPackageSourceMimeMap(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	mimeOverrides = map[string]string{".zip": "application/x-zip-compressed"}
+
+	uploadPackageSource()
+
+	if mime := api.uploadUrlQuery.Get("mime"); mime != "application/x-zip-compressed" {
+		t.Errorf("upload url requested for %q, want the mapped type", mime)
+	}
+	if api.contentType != "application/x-zip-compressed" {
+		t.Errorf("uploaded with %q, want the mapped type", api.contentType)
+	}
+}
+
+func TestCheckVersionMismatch(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+
+	if err := checkVersionMismatch(project, plugin); err != nil {
+		t.Errorf("matching versions failed the check: %v", err)
+	}
+
+	descriptor := filepath.Join("Plugins", "Plug", "Plug.uplugin")
+	if err := os.WriteFile(descriptor, []byte(`{"VersionName":"1.3.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkVersionMismatch(project, plugin); err == nil {
+		t.Errorf("expected the version mismatch to fail the check")
+	}
+
+	// The descriptor without a version is skipped
+	if err := os.WriteFile(descriptor, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkVersionMismatch(project, plugin); err != nil {
+		t.Errorf("unavailable plugin version failed the check: %v", err)
+	}
+
+	// The project without a version is skipped
+	if err := os.Remove(filepath.Join("Config", "DefaultGame.ini")); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkVersionMismatch(project, plugin); err != nil {
+		t.Errorf("unavailable project version failed the check: %v", err)
+	}
+}
+
+func TestUploadPresignedFileMultipartFallback(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	allowMultipartFallback = true
+	formParams = paramsFlag{"index": "0"}
+	t.Cleanup(func() {
+		allowMultipartFallback = false
+		formParams = paramsFlag{}
+	})
+
+	var query url.Values
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/files/upload" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("failed to parse the multipart form: %v", err)
+		}
+		query = r.URL.Query()
+		form = r.MultipartForm.Value
+		fmt.Fprint(w, `{"data":{}}`)
+	}))
+	defer server.Close()
+	apiUrl = server.URL
+
+	path := filepath.Join(t.TempDir(), "notes.md")
+	if err := os.WriteFile(path, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]string{"release-version": "1.2.0", "version": "3"}
+	_, _, err := uploadPresignedFile(uuid.Must(uuid.NewV4()), "release_notes", "text/markdown", path, "notes.md", 5, params)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	for key, value := range params {
+		if got := query.Get(key); got != value {
+			t.Errorf("query param %s = %q, want %q", key, got, value)
+		}
+		if _, ok := form[key]; ok {
+			t.Errorf("upload url param %s was sent as a form field", key)
+		}
+	}
+	if got := form["index"]; len(got) != 1 || got[0] != "0" {
+		t.Errorf("form field index = %v, want [0]", got)
+	}
+}
+
+func TestOnlyTasks(t *testing.T) {
+	if !onlyTasks("pruneReleases,checkUpdate", taskPruneReleases, taskCheckUpdate) {
+		t.Errorf("app tasks reported as not app tasks only")
+	}
+	if onlyTasks("pruneReleases,uploadPackageSource", taskPruneReleases, taskCheckUpdate) {
+		t.Errorf("combined tasks reported as app tasks only")
+	}
+}
+
+// chunkHook records the chunks logged by the multipart upload
+type chunkHook struct {
+	mu     sync.Mutex
+	chunks []int64
+}
+
+func (h *chunkHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.DebugLevel}
+}
+
+func (h *chunkHook) Fire(entry *logrus.Entry) error {
+	var from, to int64
+	if _, err := fmt.Sscanf(entry.Message, "sending bytes '%d' to '%d'", &from, &to); err == nil {
+		h.mu.Lock()
+		h.chunks = append(h.chunks, to-from)
+		h.mu.Unlock()
+	}
+	return nil
+}
+
+func TestUploadPathsUseReadBufferSize(t *testing.T) {
+	api := newMockApi(t)
+	setupUploadFixture(t, api)
+	readBufferSize = 2 * minChunkSize
+	want := []int64{2 * minChunkSize, 2 * minChunkSize, minChunkSize}
+
+	hook := &chunkHook{}
+	hooks := logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+	logrus.AddHook(hook)
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() {
+		logrus.SetLevel(level)
+		logrus.StandardLogger().ReplaceHooks(hooks)
+	})
+
+	path := filepath.Join(t.TempDir(), "content.zip")
+	if err := os.WriteFile(path, make([]byte, 5*minChunkSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, buffers := range []int{1, 4} {
+		readBuffers = buffers
+
+		// Every chunk is reported as the upload progress with the debug level
+		var uploaded []int64
+		var previous int64
+		progressFunc = func(event ProgressEvent) {
+			if event.Operation == progressUpload {
+				uploaded = append(uploaded, event.Current-previous)
+				previous = event.Current
+			}
+		}
+		if _, _, err := uploadPresignedFile(entityId, "uplugin_content", "application/zip", path, "content.zip", 5*minChunkSize, nil); err != nil {
+			t.Fatalf("presigned upload failed: %v", err)
+		}
+		if fmt.Sprint(uploaded) != fmt.Sprint(want) {
+			t.Errorf("presigned upload with %d read buffers sent chunks %v, want %v", buffers, uploaded, want)
+		}
+
+		hook.chunks = nil
+		if err := uploadEntityFile(entityId, "uplugin_content", "application/zip", path, "content.zip", nil, nil, defaultFileFieldName); err != nil {
+			t.Fatalf("multipart upload failed: %v", err)
+		}
+		if fmt.Sprint(hook.chunks) != fmt.Sprint(want) {
+			t.Errorf("multipart upload with %d read buffers sent chunks %v, want %v", buffers, hook.chunks, want)
+		}
+	}
+}
+
+func TestUploadEntityFileFieldName(t *testing.T) {
+	var body []byte
+	var contentType string
+	var contentLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		contentLength = r.ContentLength
+		fmt.Fprint(w, `{"data":{}}`)
+	}))
+	defer server.Close()
+	apiUrl = server.URL
+	httpClient = &http.Client{}
+	readBufferSize = minChunkSize
+	readBuffers = 1
+
+	path := filepath.Join(t.TempDir(), "Level.umap")
+	if err := os.WriteFile(path, []byte("level"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := uploadEntityFile(uuid.Must(uuid.NewV4()), "uplugin_content", "application/octet-stream", path, "Level.umap", nil, map[string]string{"index": "0"}, "content")
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		t.Fatalf("content type %q is not a multipart form with a boundary", contentType)
+	}
+	boundary := params["boundary"]
+	if !bytes.HasPrefix(body, []byte("--"+boundary+"\r\n")) || !bytes.HasSuffix(body, []byte("\r\n--"+boundary+"--\r\n")) {
+		t.Errorf("body is not enclosed in the boundary %s: %q", boundary, body)
+	}
+	if contentLength != int64(len(body)) {
+		t.Errorf("content length %d does not match the body length %d", contentLength, len(body))
+	}
+
+	form, err := multipart.NewReader(bytes.NewReader(body), boundary).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("failed to parse the multipart form: %v", err)
+	}
+	if got := form.Value["index"]; len(got) != 1 || got[0] != "0" {
+		t.Errorf("form field index = %v, want [0]", got)
+	}
+	if _, ok := form.File[defaultFileFieldName]; ok {
+		t.Errorf("file sent with the default field name")
+	}
+	files := form.File["content"]
+	if len(files) != 1 || files[0].Filename != "Level.umap" {
+		t.Fatalf("file field content = %v, want Level.umap", files)
+	}
+	f, err := files[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if b, _ := io.ReadAll(f); string(b) != "level" {
+		t.Errorf("file content %q, want level", b)
+	}
+}
+
+func TestGetEntityFileUploadUrlMissingId(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"no id", `{"data":{"type":"uplugin_content","url":"https://storage/content.zip"}}`, "missing id"},
+		{"nil id", `{"data":{"id":"00000000-0000-0000-0000-000000000000","url":"https://storage/content.zip"}}`, "missing id"},
+		{"no id and url", `{"data":{},"status":"error","message":"no storage"}`, "missing id, url, status: error, message: no storage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+			apiUrl = server.URL
+			httpClient = &http.Client{}
+
+			_, err := getEntityFileUploadUrl(uuid.Must(uuid.NewV4()), "uplugin_content", "application/zip", 1, "content.zip", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want the malformed response error with %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"os"
+	"sync"
+)
+
+type ManifestFile struct {
+	Id           *uuid.UUID `json:"id,omitempty"`
+	Type         string     `json:"type"`
+	Mime         string     `json:"mime"`
+	Size         int64      `json:"size"`
+	Hash         string     `json:"hash"` // hex encoded SHA-256 of the file content
+	OriginalPath string     `json:"originalPath"`
+}
+
+type Manifest struct {
+	EntityId uuid.UUID      `json:"entityId"`
+	Version  string         `json:"version,omitempty"` // project or plugin version the files were uploaded for
+	Commit   string         `json:"commit,omitempty"`  // git commit the files were built from
+	Branch   string         `json:"branch,omitempty"`
+	Files    []ManifestFile `json:"files"`
+
+	EngineVersion string `json:"engineVersion,omitempty"` // engine version the files were built with
+}
+
+var manifest Manifest
+
+// hashFile returns the hex encoded SHA-256 of the file content, using the checksum cache if loaded
+func hashFile(path string) (string, error) {
+	if checksumCache != nil {
+		return checksumCache.hash(path)
+	}
+	return computeFileHash(path)
+}
+
+// hashFiles hashes the files with up to hashConcurrency files hashed in parallel, the hashes are returned in the order of the paths
+func hashFiles(paths []string) ([]string, error) {
+	hashes := make([]string, len(paths))
+	errs := make([]error, len(paths))
+
+	workers := hashConcurrency
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				hashes[i], errs[i] = hashFile(paths[i])
+			}
+		}()
+	}
+
+	for i := range paths {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	// Report the error of the first failed file so the result does not depend on the scheduling
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash file %s: %w", paths[i], err)
+		}
+	}
+
+	return hashes, nil
+}
+
+// computeFileHash calculates the hex encoded SHA-256 of the file content
+func computeFileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return hashReader(file)
+}
+
+// hashReader calculates the hex encoded SHA-256 of the content read until EOF
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// computeContentMD5 calculates the base64 encoded MD5 of the request body as expected by the Content-MD5 header
+func computeContentMD5(body io.Reader) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// addFile hashes the local file and records it in the manifest, the file metadata returned by the API (if any) takes precedence over the requested values
+func (m *Manifest) addFile(path string, fileType string, mime string, originalPath string, metadata *FileMetadata) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	file := ManifestFile{
+		Type:         fileType,
+		Mime:         mime,
+		Size:         fi.Size(),
+		Hash:         hash,
+		OriginalPath: originalPath,
+	}
+
+	if metadata != nil {
+		file.Id = metadata.Id
+		if metadata.Type != "" {
+			file.Type = metadata.Type
+		}
+		if metadata.Mime != nil && *metadata.Mime != "" {
+			file.Mime = *metadata.Mime
+		}
+		if metadata.OriginalPath != "" {
+			file.OriginalPath = metadata.OriginalPath
+		}
+	}
+
+	m.Files = append(m.Files, file)
+	return nil
+}
+
+// readManifest loads the manifest from the json file
+func readManifest(path string) (Manifest, error) {
+	var m Manifest
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return m, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return m, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// writeManifest saves the manifest as an indented json file
+func writeManifest(path string, m Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize manifest: %w", err)
+	}
+
+	err = os.WriteFile(path, b, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
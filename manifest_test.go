@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func BenchmarkHashFiles(b *testing.B) {
+	dir := b.TempDir()
+	content := make([]byte, 1024*1024)
+	paths := make([]string, 32)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("%d.uasset", i))
+		if err := os.WriteFile(paths[i], content, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	saved := hashConcurrency
+	b.Cleanup(func() { hashConcurrency = saved })
+
+	for _, concurrency := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			hashConcurrency = concurrency
+			b.SetBytes(int64(len(content) * len(paths)))
+			for i := 0; i < b.N; i++ {
+				if _, err := hashFiles(paths); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// UploadPart is the presigned url of a multipart upload part
+type UploadPart struct {
+	Number int    `json:"number"`
+	Url    string `json:"url"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// multipartStorage uploads the content by parts to the S3 presigned part urls in parallel, then completes the upload with the presigned complete url
+type multipartStorage struct {
+	parts       []UploadPart
+	partSize    int64
+	concurrency int
+	headUrl     string // presigned url verifying the completed object size
+}
+
+// partUpload is the content of a part read from the stream
+type partUpload struct {
+	index int
+	part  UploadPart
+	data  []byte
+}
+
+func (s multipartStorage) Upload(ctx context.Context, url string, reader io.Reader, size int64, contentType string) error {
+	if s.partSize <= 0 {
+		return fmt.Errorf("invalid multipart upload part size %d", s.partSize)
+	}
+	if count := (size + s.partSize - 1) / s.partSize; count != int64(len(s.parts)) {
+		return fmt.Errorf("%d parts of %d bytes expected for %d bytes, got %d part urls", count, s.partSize, size, len(s.parts))
+	}
+
+	concurrency := s.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// The first failed part cancels the others
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		sent     int64
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	etags := make([]string, len(s.parts))
+	uploads := make(chan partUpload)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for upload := range uploads {
+				etag, err := uploadPart(ctx, upload.part, upload.data)
+				if err != nil {
+					fail(fmt.Errorf("failed to upload part %d: %w", upload.part.Number, err))
+					continue
+				}
+
+				// Parts complete in any order, the upload is completed by part number
+				mu.Lock()
+				etags[upload.index] = etag
+				sent += int64(len(upload.data))
+				logUploadStatus(sent, size)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Read the parts sequentially, every worker may hold a part while the next part is read and waits for a free worker,
+	// so up to concurrency+1 parts are held in memory
+	remaining := size
+	for i, part := range s.parts {
+		n := s.partSize
+		if remaining < n {
+			n = remaining
+		}
+		remaining -= n
+
+		data := make([]byte, n)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			fail(fmt.Errorf("failed to read part %d: %w", part.Number, err))
+			break
+		}
+
+		select {
+		case uploads <- partUpload{index: i, part: part, data: data}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(uploads)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if remaining != 0 {
+		return fmt.Errorf("%d parts of %d bytes do not cover %d bytes, %d bytes were not uploaded", len(s.parts), s.partSize, size, remaining)
+	}
+
+	err := completeMultipart(ctx, url, s.parts, etags)
+	if err != nil {
+		return err
+	}
+
+	// The ETag of the completed object is not the content MD5, the parts are verified by the size of the assembled object
+	err = verifyObjectSize(ctx, s.headUrl, size)
+	if err != nil {
+		return fmt.Errorf("failed to verify the completed upload: %w", err)
+	}
+
+	return nil
+}
+
+// uploadPart sends the part content to the part url and returns the part ETag
+func uploadPart(ctx context.Context, part UploadPart, data []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", part.Url, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = int64(len(data))
+
+	resp, err := sendStorageRequest(req, "failed to upload a part")
+	if err != nil {
+		return "", err
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("no ETag returned")
+	}
+
+	return etag, nil
+}
+
+// completeMultipart assembles the uploaded parts into the object
+func completeMultipart(ctx context.Context, url string, parts []UploadPart, etags []string) error {
+	complete := completeMultipartUpload{}
+	for i, part := range parts {
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: part.Number, ETag: etags[i]})
+	}
+
+	b, err := xml.Marshal(complete)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the completed parts: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml")
+
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	// S3 may report a failed completion with a 200 status code
+	if resp.StatusCode >= 400 || strings.Contains(string(body), "<Error>") {
+		return newApiError("failed to complete a multipart upload", resp.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const notifyStatusSuccess = "success"
+const notifyStatusFailure = "failure"
+
+// Time to wait for the notification endpoint, the notification must not hold the run
+const notifyTimeout = 10 * time.Second
+
+// NotifyPayload is posted to the notification url when the run completes
+type NotifyPayload struct {
+	EntityId  string  `json:"entityId"`
+	Plugin    string  `json:"plugin"`
+	Task      string  `json:"task"`
+	Version   string  `json:"version,omitempty"`
+	Status    string  `json:"status"`
+	Duration  float64 `json:"duration"` // seconds
+	FileCount int     `json:"fileCount"`
+}
+
+var (
+	notifyOnce      sync.Once
+	notifyStartTime = time.Now()
+
+	// inPluginTasks is set while the failures of the plugin tasks are recovered, these do not complete the run
+	inPluginTasks bool
+)
+
+// notifyCompletion posts the run result to the notification url once, failures are logged only
+func notifyCompletion(status string) {
+	if notifyUrl == "" || inPluginTasks {
+		return
+	}
+
+	notifyOnce.Do(func() {
+		payload := NotifyPayload{
+			EntityId:  entityId.String(),
+			Plugin:    plugin,
+			Task:      summary.Task,
+			Version:   summary.Version,
+			Status:    status,
+			Duration:  time.Since(notifyStartTime).Seconds(),
+			FileCount: len(summary.Files),
+		}
+
+		err := postNotification(notifyUrl, payload)
+		if err != nil {
+			logger.Warningf("failed to send the notification: %v", err)
+			return
+		}
+		logger.Debugf("sent the %s notification", status)
+	})
+}
+
+func postNotification(url string, payload NotifyPayload) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to serialize notification: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read the response body: %w", err)
+		}
+		return newApiError("failed to notify", resp.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gabriel-vasile/mimetype"
+	"io"
+	"os"
+)
+
+// Package path of the prebuilt archive read from stdin
+const packagePathStdin = "-"
+
+// isZipMime reports whether the detected mime is zip or a zip based format
+func isZipMime(detected *mimetype.MIME) bool {
+	for m := detected; m != nil; m = m.Parent() {
+		if m.Is("application/zip") {
+			return true
+		}
+	}
+	return false
+}
+
+// preparePackageArchive returns the path and the size of the prebuilt package archive.
+// The archive read from stdin is buffered to the temp path first as the upload needs the content length.
+func preparePackageArchive(packagePath string, tempPath string) (string, int64, error) {
+	path := packagePath
+	if packagePath == packagePathStdin {
+		file, err := os.Create(tempPath)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to create a temp archive file: %w", err)
+		}
+
+		n, err := io.Copy(file, os.Stdin)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to read the archive from stdin: %w", err)
+		}
+
+		logger.Infof("read %d bytes of the package archive from stdin", n)
+		path = tempPath
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return "", 0, fmt.Errorf("%s is not a file", path)
+	}
+	if fi.Size() == 0 {
+		return "", 0, fmt.Errorf("%s is empty", path)
+	}
+
+	// Check the format from the initial bytes, the archive is uploaded as a zip
+	detected, err := mimetype.DetectFile(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to detect mime: %w", err)
+	}
+	if !isZipMime(detected) {
+		return "", 0, fmt.Errorf("%s is not a zip archive, detected %s", path, detected.String())
+	}
+
+	return path, fi.Size(), nil
+}
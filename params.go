@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// paramsFlag collects repeated key=value flags
+type paramsFlag map[string]string
+
+func (p paramsFlag) String() string {
+	var pairs []string
+	for key, value := range p {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (p paramsFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got '%s'", value)
+	}
+	p[key] = val
+	return nil
+}
+
+// fileMetadataParams returns the multipart form params the API expects to describe an uploaded file: type, version, index and originalPath.
+// The API has a unique index over the entity, type, index and original path, so index and original path are only sent along with an explicit
+// version, otherwise re-uploading the same file would be rejected. The platform and deployment tell apart the files of the same release uploaded per platform.
+func fileMetadataParams(metadata FileMetadata) map[string]string {
+	params := map[string]string{}
+	if metadata.Type != "" {
+		params["type"] = metadata.Type
+	}
+	if metadata.Version > 0 {
+		params["version"] = strconv.FormatInt(int64(metadata.Version), 10)
+		params["index"] = strconv.FormatInt(int64(metadata.Index), 10)
+		if metadata.OriginalPath != "" {
+			params["originalPath"] = metadata.OriginalPath
+		}
+	}
+	if metadata.Platform != "" {
+		params["platform"] = metadata.Platform
+	}
+	if metadata.Deployment != "" {
+		params["deployment-type"] = metadata.Deployment
+	}
+	return params
+}
+
+// mergeParams returns the union of the param maps, the later maps take precedence
+func mergeParams(maps ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, m := range maps {
+		for key, value := range m {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// stringsFlag collects repeated flag values
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
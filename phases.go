@@ -0,0 +1,25 @@
+package main
+
+import (
+	"time"
+)
+
+const phaseArchive = "archive"
+const phasePresign = "presign"
+const phaseUpload = "upload"
+const phaseJobCreate = "job-create"
+
+// startPhase starts timing the phase, the returned func stops it, records the duration in the summary and warns if the phase was slow
+func startPhase(name string) func() {
+	start := time.Now()
+	return func() {
+		duration := time.Since(start)
+		summary.addPhase(name, duration)
+
+		if slowPhaseThreshold > 0 && duration > slowPhaseThreshold {
+			logger.WithField("phase", name).Warningf("%s phase took %s, longer than %s", name, duration.Round(time.Millisecond), slowPhaseThreshold)
+		} else {
+			logger.WithField("phase", name).Debugf("%s phase took %s", name, duration.Round(time.Millisecond))
+		}
+	}
+}
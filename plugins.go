@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// taskFailure is raised instead of exiting the process when a task fails while running the tasks for multiple plugins
+type taskFailure struct {
+	code int
+}
+
+// fatalMessageHook keeps the message of the last fatal log entry to report it as the plugin failure reason
+type fatalMessageHook struct {
+	message string
+}
+
+func (h *fatalMessageHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.FatalLevel}
+}
+
+func (h *fatalMessageHook) Fire(entry *logrus.Entry) error {
+	h.message = entry.Message
+	return nil
+}
+
+// findPlugins returns the names of the project plugins with a .uplugin file matching the glob pattern
+func findPlugins(projectName string, pattern string) ([]string, error) {
+	projectDir, err := getProjectDir(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := os.ReadDir(filepath.Join(projectDir, "Plugins"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins dir: %w", err)
+	}
+
+	var plugins []string
+	for _, item := range items {
+		if !item.IsDir() {
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(projectDir, "Plugins", item.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plugin dir: %w", err)
+		}
+
+		for _, file := range files {
+			if file.IsDir() || strings.ToLower(filepath.Ext(file.Name())) != ".uplugin" {
+				continue
+			}
+
+			name := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+			if ok, _ := filepath.Match(pattern, name); ok && strings.EqualFold(name, item.Name()) {
+				plugins = append(plugins, item.Name())
+				break
+			}
+		}
+	}
+
+	return plugins, nil
+}
+
+// runPluginTasks runs the tasks for the current plugin, a failed task stops the plugin tasks without exiting the process
+func runPluginTasks(tasks []string) (err error) {
+	std := logrus.StandardLogger()
+	hooks := logrus.LevelHooks{}
+	for level, levelHooks := range std.Hooks {
+		hooks[level] = append([]logrus.Hook{}, levelHooks...)
+	}
+
+	hook := &fatalMessageHook{}
+	std.AddHook(hook)
+	exitFunc := std.ExitFunc
+	std.ExitFunc = func(code int) {
+		panic(taskFailure{code: code})
+	}
+	inPluginTasks = true
+
+	defer func() {
+		inPluginTasks = false
+		std.ExitFunc = exitFunc
+		std.ReplaceHooks(hooks)
+
+		if r := recover(); r != nil {
+			failure, ok := r.(taskFailure)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("%s (exit code %d)", hook.message, failure.code)
+		}
+	}()
+
+	runTasks(tasks)
+	return nil
+}
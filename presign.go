@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Presigned urls expiring sooner than this are refreshed before the upload starts
+const presignedUrlRefreshMargin = 1 * time.Minute
+
+// presignedUrlExpiry returns the expiry time of the AWS Signature V4 presigned url if it has the X-Amz-Date and X-Amz-Expires params
+func presignedUrlExpiry(presignedUrl string) (time.Time, bool) {
+	u, err := url.Parse(presignedUrl)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	query := u.Query()
+	signedAt, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return signedAt.Add(time.Duration(expires) * time.Second), true
+}
+
+// presignedUrlExpiresSoon reports whether the presigned url lapses within the refresh margin, urls with unknown expiry are considered valid
+func presignedUrlExpiresSoon(presignedUrl string) bool {
+	expiry, ok := presignedUrlExpiry(presignedUrl)
+	return ok && time.Until(expiry) < presignedUrlRefreshMargin
+}
+
+// isPresignedUrlRejected reports whether the storage refused the presigned url, usually because it has expired
+func isPresignedUrlRejected(err error) bool {
+	var apiErr *ApiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
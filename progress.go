@@ -0,0 +1,26 @@
+package main
+
+import "veverse-sdk-automation/progress"
+
+// Operations reporting the progress, also used as the progress log line prefixes
+const progressArchive = progress.OperationArchive
+const progressUpload = progress.OperationUpload
+
+// ProgressEvent is the number of processed bytes of the operation, exported by the progress package
+type ProgressEvent = progress.Event
+
+// ProgressFunc receives the progress events of the archive and upload operations
+type ProgressFunc = progress.Func
+
+// progressFunc handles the progress events, replace it to consume the events programmatically instead of parsing the log
+var progressFunc ProgressFunc = logProgressEvent
+
+// logProgressEvent logs the progress event as the operation prefix followed by the current:total|ratio
+func logProgressEvent(event ProgressEvent) {
+	logger.Infof("%s%d:%d|%.3f", event.Operation, event.Current, event.Total, float64(event.Current)/float64(event.Total))
+}
+
+// reportProgress passes the progress of the operation to the progress handler
+func reportProgress(operation string, current int64, total int64) {
+	progressFunc(ProgressEvent{Operation: operation, Current: current, Total: total})
+}
@@ -0,0 +1,19 @@
+// Package progress defines the progress events reported by veverse-sdk-automation while archiving and uploading the package content,
+// so the tools running the uploads can consume them programmatically instead of parsing the log.
+package progress
+
+// Operations reporting the progress, also used as the progress log line prefixes by the CLI
+const (
+	OperationArchive = "a"
+	OperationUpload  = "u"
+)
+
+// Event is the number of processed bytes of the operation
+type Event struct {
+	Operation string
+	Current   int64
+	Total     int64
+}
+
+// Func receives the progress events of the archive and upload operations
+type Func func(event Event)
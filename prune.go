@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"github.com/gofrs/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// Number of releases requested per page
+const releasesPageSize = 100
+
+type ReleasesContainer struct {
+	Data []ReleaseMetadata `json:"data"`
+}
+
+// getAppReleases fetches all the app releases page by page
+func getAppReleases(appId uuid.UUID) ([]ReleaseMetadata, error) {
+	var releases []ReleaseMetadata
+	for offset := 0; ; offset += releasesPageSize {
+		query := url.Values{"offset": {strconv.Itoa(offset)}, "limit": {strconv.Itoa(releasesPageSize)}}
+		reqUrl := fmt.Sprintf("%s/apps/%s/releases?%s", apiUrl, appId.String(), query.Encode())
+
+		req, err := http.NewRequest("GET", reqUrl, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to instantiate request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+		// Process the HTTP request
+		client := httpClient
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the response body: %w", err)
+		}
+
+		if resp.StatusCode >= 400 {
+			return nil, newApiError("failed to get app releases", resp.StatusCode, body)
+		}
+
+		var container ReleasesContainer
+		err = json.Unmarshal(body, &container)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse releases json: %w", err)
+		}
+
+		releases = append(releases, container.Data...)
+		if len(container.Data) < releasesPageSize {
+			return releases, nil
+		}
+	}
+}
+
+// deleteRelease deletes the release entity with its files
+func deleteRelease(releaseId uuid.UUID) error {
+	reqUrl := fmt.Sprintf("%s/releases/%s", apiUrl, releaseId.String())
+
+	req, err := http.NewRequest("DELETE", reqUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+	// Process the HTTP request
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read the response body: %w", err)
+		}
+		return newApiError("failed to delete a release", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// releaseNewer reports whether the release a is newer than b. The releases are ordered by a single key: the releases with a valid version
+// come first ordered by the version, then by the creation time, the releases without the creation time come last.
+func releaseNewer(a ReleaseMetadata, b ReleaseMetadata) bool {
+	va, errA := semver.NewVersion(a.Version)
+	vb, errB := semver.NewVersion(b.Version)
+	if (errA == nil) != (errB == nil) {
+		return errA == nil
+	}
+	if errA == nil && !va.Equal(vb) {
+		return va.GreaterThan(vb)
+	}
+
+	if (a.CreatedAt != nil) != (b.CreatedAt != nil) {
+		return a.CreatedAt != nil
+	}
+	return a.CreatedAt != nil && a.CreatedAt.After(*b.CreatedAt)
+}
+
+// selectReleasesToPrune returns the releases except the keep most recent ones and the ones matching the constraint (if any)
+func selectReleasesToPrune(releases []ReleaseMetadata, keep int, constraint *semver.Constraints) []ReleaseMetadata {
+	sorted := make([]ReleaseMetadata, len(releases))
+	copy(sorted, releases)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return releaseNewer(sorted[i], sorted[j])
+	})
+
+	var pruned []ReleaseMetadata
+	for i, release := range sorted {
+		if release.Id == nil || i < keep {
+			continue
+		}
+
+		if constraint != nil {
+			if v, err := semver.NewVersion(release.Version); err == nil && constraint.Check(v) {
+				logger.Debugf("keeping release %s version %s matching the constraint", release.Id.String(), release.Version)
+				continue
+			}
+		}
+
+		pruned = append(pruned, release)
+	}
+
+	return pruned
+}
+
+// pruneReleases deletes the app releases not kept by the retention policy, only reports them unless confirmed
+func pruneReleases(appId uuid.UUID, keep int, constraint *semver.Constraints, yes bool) error {
+	releases, err := getAppReleases(appId)
+	if err != nil {
+		return err
+	}
+
+	pruned := selectReleasesToPrune(releases, keep, constraint)
+	logger.Infof("%d of %d releases to prune", len(pruned), len(releases))
+
+	for _, release := range pruned {
+		if !yes {
+			logger.Infof("would prune release %s version %s", release.Id.String(), release.Version)
+			summary.addPrunedRelease(release, false)
+			continue
+		}
+
+		err = deleteRelease(*release.Id)
+		if err != nil {
+			return fmt.Errorf("failed to prune release %s: %w", release.Id.String(), err)
+		}
+		logger.Infof("pruned release %s version %s", release.Id.String(), release.Version)
+		summary.addPrunedRelease(release, true)
+	}
+
+	if !yes && len(pruned) > 0 {
+		logger.Infof("dry run, pass -yes to delete the releases")
+	}
+
+	return nil
+}
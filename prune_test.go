@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/gofrs/uuid"
+	"testing"
+	"time"
+)
+
+func TestSelectReleasesToPrune(t *testing.T) {
+	now := time.Now()
+	release := func(version string, age time.Duration) ReleaseMetadata {
+		id := uuid.Must(uuid.NewV4())
+		createdAt := now.Add(-age)
+		r := ReleaseMetadata{Version: version}
+		r.Id = &id
+		r.CreatedAt = &createdAt
+		return r
+	}
+
+	// Comparing by the version only when both have one made these three releases a cycle
+	v2 := release("2.0.0", 3*time.Hour)
+	v1 := release("1.0.0", time.Hour)
+	unversioned := release("", 2*time.Hour)
+	undated := release("", 0)
+	undated.CreatedAt = nil
+
+	releases := []ReleaseMetadata{unversioned, v1, undated, v2}
+	for i := range releases {
+		rotated := append(append([]ReleaseMetadata{}, releases[i:]...), releases[:i]...)
+		pruned := selectReleasesToPrune(rotated, 2, nil)
+		if len(pruned) != 2 || *pruned[0].Id != *unversioned.Id || *pruned[1].Id != *undated.Id {
+			t.Errorf("pruned %v of %v, want the unversioned and undated releases", pruned, rotated)
+		}
+	}
+}
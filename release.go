@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"net/http"
+	"os"
+	"unicode/utf8"
+)
+
+const maxReleaseNameLength = 255
+const maxReleaseDescriptionLength = 4096
+
+// readReleaseMetadata reads the release name, description and visibility from the json metadata file
+func readReleaseMetadata(path string) (ReleaseMetadata, error) {
+	var metadata ReleaseMetadata
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return metadata, fmt.Errorf("failed to read metadata file: %w", err)
+	}
+
+	err = json.Unmarshal(b, &metadata)
+	if err != nil {
+		return metadata, fmt.Errorf("failed to parse metadata file: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// validate checks the name and description lengths against the api limits
+func (m ReleaseMetadata) validate() error {
+	if m.Name != nil {
+		if n := utf8.RuneCountInString(*m.Name); n > maxReleaseNameLength {
+			return fmt.Errorf("name is %d characters long, the limit is %d", n, maxReleaseNameLength)
+		}
+	}
+
+	if m.Description != nil {
+		if n := utf8.RuneCountInString(*m.Description); n > maxReleaseDescriptionLength {
+			return fmt.Errorf("description is %d characters long, the limit is %d", n, maxReleaseDescriptionLength)
+		}
+	}
+
+	return nil
+}
+
+// setEntityVisibility makes the entity public or private, returns the entity metadata with the visibility applied by the api
+func setEntityVisibility(entityId uuid.UUID, public bool) (EntityMetadata, error) {
+	reqUrl := fmt.Sprintf("%s/entities/%s", apiUrl, entityId.String())
+
+	b, err := json.Marshal(EntityMetadata{Public: &public})
+	if err != nil {
+		return EntityMetadata{}, fmt.Errorf("failed to serialize entity request: %w", err)
+	}
+
+	req, err := http.NewRequest("PATCH", reqUrl, bytes.NewReader(b))
+	if err != nil {
+		return EntityMetadata{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", currentToken()))
+
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return EntityMetadata{}, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return EntityMetadata{}, fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return EntityMetadata{}, newApiError("failed to update the entity", resp.StatusCode, body)
+	}
+
+	var container EntityMetadataContainer
+	err = json.Unmarshal(body, &container)
+	if err != nil {
+		return EntityMetadata{}, fmt.Errorf("failed to parse entity json: %w", err)
+	}
+
+	return container.EntityMetadata, nil
+}
+
+// applyReleaseVisibility sets the visibility requested with -public or -private on the release entity and reports the visibility returned by the api
+func applyReleaseVisibility(entityId uuid.UUID, public *bool) {
+	if public == nil {
+		return
+	}
+
+	var entity EntityMetadata
+	_, err := withRetry("release visibility update", func() error {
+		var err error
+		entity, err = setEntityVisibility(entityId, *public)
+		return err
+	})
+	if err != nil {
+		withErrorFields(err).Fatalf("failed to set the release visibility: %v", err)
+	}
+
+	summary.Public = entity.Public
+	if entity.Public == nil || *entity.Public != *public {
+		logger.Warningf("the api did not apply the requested release visibility")
+	}
+}
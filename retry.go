@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const retryBaseDelay = 1 * time.Second
+
+// retryStats holds the number of attempts and the total delay spent waiting between them
+type retryStats struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// retryPredicate reports whether the failed operation may be repeated
+type retryPredicate func(err error) bool
+
+// retryAnyError repeats the idempotent operations on any error, e.g. the uploads replacing the same object
+func retryAnyError(error) bool {
+	return true
+}
+
+// retryNotProcessed repeats the non-idempotent operations only if the request failed before a response arrived or the api explicitly did not process it.
+// Other server errors and the failures to read or parse a response are ambiguous as the request may have been processed, repeating it could create duplicates.
+func retryNotProcessed(err error) bool {
+	// The http client returns the transport errors as url errors, the response body read errors are returned as is
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// withRetry calls fn until it succeeds or the configured number of retries is exhausted, doubling the delay between attempts
+func withRetry(name string, fn func() error) (stats retryStats, err error) {
+	return withRetryIf(name, retryAnyError, fn)
+}
+
+// withRetryIf calls fn until it succeeds, the error is not retryable or the configured number of retries is exhausted, doubling the delay between attempts
+func withRetryIf(name string, retryable retryPredicate, fn func() error) (stats retryStats, err error) {
+	delay := retryBaseDelay
+	for {
+		stats.Attempts++
+		err = fn()
+		if err == nil || stats.Attempts > retries {
+			return stats, err
+		}
+		if !retryable(err) {
+			withErrorFields(err).Warningf("%s failed (attempt %d of %d), not retrying: %v", name, stats.Attempts, retries+1, err)
+			return stats, err
+		}
+
+		withErrorFields(err).Warningf("%s failed (attempt %d of %d), retrying in %s: %v", name, stats.Attempts, retries+1, delay, err)
+		time.Sleep(delay)
+		stats.Delay += delay
+		delay *= 2
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryNotProcessed(t *testing.T) {
+	transportErr := &url.Error{Op: "Post", URL: "http://api/jobs/package", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"transport", fmt.Errorf("failed to send request: %w", transportErr), true},
+		{"too many requests", newApiError("failed to create package job", http.StatusTooManyRequests, nil), true},
+		{"unavailable", newApiError("failed to create package job", http.StatusServiceUnavailable, nil), true},
+		{"server error", newApiError("failed to create package job", http.StatusInternalServerError, nil), false},
+		{"body read", fmt.Errorf("failed to read the response body: %w", io.ErrUnexpectedEOF), false},
+		{"json parse", fmt.Errorf("failed to parse jobs json: %w", errors.New("unexpected end of JSON input")), false},
+	}
+
+	for _, test := range tests {
+		if got := retryNotProcessed(test.err); got != test.want {
+			t.Errorf("%s: retryNotProcessed() = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCreatePackageJobsNotRetriedAfterResponse(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"data":[`)
+	}))
+	defer server.Close()
+	apiUrl = server.URL
+	httpClient = &http.Client{}
+	retries = 2
+	t.Cleanup(func() { retries = 0 })
+
+	stats, err := withRetryIf("package job creation", retryNotProcessed, func() error {
+		_, err := createPackageJobs(uuid.Must(uuid.NewV4()), nil, ReleaseMetadata{}, SourceRevision{}, "", nil, "key")
+		return err
+	})
+	if err == nil {
+		t.Fatal("expected the malformed response to fail the job creation")
+	}
+	if stats.Attempts != 1 || requests != 1 {
+		t.Errorf("job creation repeated after a response, %d attempts, %d requests", stats.Attempts, requests)
+	}
+}
+
+func TestCategorizedErrorTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 10 * time.Millisecond
+	client := &http.Client{Transport: tokenRefreshTransport{base: transport}}
+	_, err := client.Get(server.URL)
+
+	// The url error checks the timeout of the error returned by the transport without unwrapping it
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || !urlErr.Timeout() {
+		t.Errorf("categorized transport error %v is not reported as a timeout", err)
+	}
+	if !errors.Is(err, ErrNetwork) {
+		t.Errorf("transport error %v lost the network category", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// SourceRevision is the git commit and branch the uploaded package was built from
+type SourceRevision struct {
+	Commit string `json:"commit,omitempty"`
+	Branch string `json:"branch,omitempty"`
+}
+
+// Commit and branch environment variables set by the CI systems, in the order of precedence
+var (
+	commitEnvVars = []string{"GITHUB_SHA", "CI_COMMIT_SHA", "GIT_COMMIT", "BUILD_SOURCEVERSION"}
+	branchEnvVars = []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "GITHUB_REF", "CI_COMMIT_REF_NAME", "GIT_BRANCH", "BUILD_SOURCEBRANCH"}
+)
+
+// firstEnv returns the value of the first set environment variable
+func firstEnv(names []string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// detectSourceRevision returns the revision with the commit and branch not set explicitly detected from the CI environment
+func detectSourceRevision(commit string, branch string) SourceRevision {
+	if commit == "" {
+		commit = firstEnv(commitEnvVars)
+	}
+	if branch == "" {
+		branch = firstEnv(branchEnvVars)
+	}
+
+	// Keep the branch name only of the full refs, e.g. refs/heads/main or origin/main
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	branch = strings.TrimPrefix(branch, "origin/")
+
+	return SourceRevision{Commit: commit, Branch: branch}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ETag of the objects uploaded with a single PUT without KMS encryption is the hex encoded MD5 of the content
+var md5ETagRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// verifyUploadedObject confirms the object uploaded with a single PUT is not truncated comparing the ETag returned by the PUT with the MD5 of the sent content.
+// The ETag of the KMS or SSE-C encrypted objects is not the MD5 of the content, these objects are checked by the size instead.
+func verifyUploadedObject(ctx context.Context, headUrl string, etag string, contentMD5 string, size int64) error {
+	etag = strings.ToLower(strings.Trim(etag, `"`))
+	if !md5ETagRegexp.MatchString(etag) {
+		logger.Debugf("uploaded object ETag is not the content MD5, verifying the size")
+		return verifyObjectSize(ctx, headUrl, size)
+	}
+
+	if etag != contentMD5 {
+		return fmt.Errorf("uploaded object ETag %s does not match the content MD5 %s", etag, contentMD5)
+	}
+
+	logger.Debugf("uploaded object ETag matches the content MD5")
+	return nil
+}
+
+// verifyObjectSize requests the uploaded object size with a HEAD request to the head url presigned by the api and compares it with the file size.
+// The upload urls are signed for the upload method only, so the size can't be verified if the api returned no head url.
+func verifyObjectSize(ctx context.Context, headUrl string, size int64) error {
+	if headUrl == "" {
+		logger.Warningf("the api returned no head url, unable to verify the uploaded object size")
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", headUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return newApiError("failed to request the uploaded object size", resp.StatusCode, nil)
+	}
+	if resp.ContentLength < 0 {
+		return fmt.Errorf("no uploaded object size returned")
+	}
+	if resp.ContentLength != size {
+		return fmt.Errorf("uploaded object size %d does not match the file size %d", resp.ContentLength, size)
+	}
+
+	logger.Debugf("uploaded object size matches the file size")
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// headServer responds to the HEAD requests with the object size and the status, counting the requests
+func headServer(t *testing.T, size int64, status int) (*httptest.Server, *int32) {
+	var heads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		atomic.AddInt32(&heads, 1)
+		if status >= 400 {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}))
+	t.Cleanup(server.Close)
+	httpClient = &http.Client{}
+	return server, &heads
+}
+
+func TestVerifyUploadedObject(t *testing.T) {
+	sum := md5.Sum([]byte("content"))
+	contentMD5 := hex.EncodeToString(sum[:])
+	kmsETag := `"2c7d2f0ec4b3b4f2f4f0a1c0e5d5a7b1-1"`
+
+	tests := []struct {
+		name      string
+		etag      string
+		headSize  int64
+		status    int
+		noHeadUrl bool
+		wantErr   string
+		wantHeads int32
+	}{
+		{name: "md5 etag", etag: `"` + contentMD5 + `"`},
+		{name: "md5 etag mismatch", etag: `"` + strings.Repeat("0", 32) + `"`, wantErr: "does not match the content MD5"},
+		{name: "kms etag", etag: kmsETag, headSize: 7, wantHeads: 1},
+		{name: "kms etag size mismatch", etag: kmsETag, headSize: 3, wantErr: "uploaded object size 3 does not match the file size 7", wantHeads: 1},
+		{name: "kms etag head rejected", etag: kmsETag, status: http.StatusForbidden, wantErr: "status code: 403", wantHeads: 1},
+		{name: "kms etag without head url", etag: kmsETag, noHeadUrl: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, heads := headServer(t, tt.headSize, tt.status)
+			headUrl := server.URL + "/object?X-Amz-Signature=head"
+			if tt.noHeadUrl {
+				headUrl = ""
+			}
+
+			err := verifyUploadedObject(context.Background(), headUrl, tt.etag, contentMD5, 7)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(heads); got != tt.wantHeads {
+				t.Errorf("%d HEAD requests, want %d", got, tt.wantHeads)
+			}
+		})
+	}
+}
+
+func TestMultipartUploadSizeMismatch(t *testing.T) {
+	content := []byte(strings.Repeat("part", 10))
+	for _, objectSize := range []int{len(content), len(content) - 4} {
+		t.Run(fmt.Sprintf("object size %d", objectSize), func(t *testing.T) {
+			var completed bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case "PUT":
+					b, _ := io.ReadAll(r.Body)
+					sum := md5.Sum(b)
+					w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+				case "POST":
+					completed = true
+					fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+				case "HEAD":
+					if !completed {
+						t.Errorf("object size requested before the completion")
+					}
+					w.Header().Set("Content-Length", strconv.Itoa(objectSize))
+				}
+			}))
+			defer server.Close()
+			httpClient = &http.Client{}
+
+			storage := multipartStorage{
+				parts:       []UploadPart{{Number: 1, Url: server.URL + "/part/1"}, {Number: 2, Url: server.URL + "/part/2"}},
+				partSize:    int64(len(content)/2 + 1),
+				concurrency: 2,
+				headUrl:     server.URL + "/object",
+			}
+			err := storage.Upload(context.Background(), server.URL+"/complete", bytes.NewReader(content), int64(len(content)), "application/zip")
+			if objectSize == len(content) && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if objectSize != len(content) && (err == nil || !strings.Contains(err.Error(), "does not match the file size")) {
+				t.Errorf("error %v, want the size mismatch", err)
+			}
+		})
+	}
+}
+
+func TestGcsResumableUploadSizeMismatch(t *testing.T) {
+	content := []byte("content")
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			w.Header().Set("Location", server.URL+"/session")
+			w.WriteHeader(http.StatusCreated)
+		case "PUT":
+			_, _ = io.Copy(io.Discard, r.Body)
+		case "HEAD":
+			w.Header().Set("Content-Length", "3")
+		}
+	}))
+	defer server.Close()
+	httpClient = &http.Client{}
+
+	storage := gcsResumableStorage{headUrl: server.URL + "/object"}
+	err := storage.Upload(context.Background(), server.URL+"/upload", bytes.NewReader(content), int64(len(content)), "application/zip")
+	if err == nil || !strings.Contains(err.Error(), "uploaded object size 3 does not match the file size 7") {
+		t.Errorf("error %v, want the size mismatch", err)
+	}
+}
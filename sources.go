@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mholt/archiver/v4"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const mergeConflictError = "error"
+const mergeConflictOverwrite = "overwrite"
+
+// contentSources returns the dirs to archive, relative content dirs are resolved against the plugin content temp dir, all its items are archived if no content dirs are given
+func contentSources(pluginContentTempDir string, contentDirs []string) ([]string, error) {
+	var sources []string
+	if len(contentDirs) == 0 {
+		items, err := os.ReadDir(pluginContentTempDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read content dir: %w", err)
+		}
+		for _, item := range items {
+			sources = append(sources, filepath.Join(pluginContentTempDir, item.Name()))
+		}
+		return sources, nil
+	}
+
+	for _, dir := range contentDirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(pluginContentTempDir, dir)
+		}
+
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat content dir: %w", err)
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("content dir %s is not a directory", dir)
+		}
+
+		sources = append(sources, filepath.Clean(dir))
+	}
+
+	return sources, nil
+}
+
+// archiveSources enumerates the files of the sources, each under its source name, returns the files and their disk paths keyed by the name in the archive.
+// Files with the same name in different sources fail the enumeration unless the merge conflict mode is overwrite, in which case the later source wins.
+func archiveSources(sources []string, symlinkMode string, mergeConflict string) ([]archiver.File, map[string]string, error) {
+	var files []archiver.File
+	paths := map[string]string{}
+	index := map[string]int{}
+
+	for _, source := range sources {
+		sourceFiles, err := archiveFilesFromDisk(map[string]string{source: ""}, symlinkMode)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, file := range sourceFiles {
+			file.NameInArchive = archiveEntryName(file.NameInArchive)
+			path := filepath.Join(filepath.Dir(source), filepath.FromSlash(file.NameInArchive))
+
+			i, exists := index[file.NameInArchive]
+			if !exists {
+				index[file.NameInArchive] = len(files)
+				files = append(files, file)
+				paths[file.NameInArchive] = path
+				continue
+			}
+
+			// Directories are merged
+			if file.IsDir() && files[i].IsDir() {
+				continue
+			}
+
+			if mergeConflict != mergeConflictOverwrite {
+				return nil, nil, fmt.Errorf("'%s' exists in multiple content dirs: %s and %s, pass -mergeConflict=overwrite to keep the last one", file.NameInArchive, paths[file.NameInArchive], path)
+			}
+
+			logger.Warningf("overwriting '%s' from %s with %s", file.NameInArchive, paths[file.NameInArchive], path)
+			files[i] = file
+			paths[file.NameInArchive] = path
+		}
+	}
+
+	return files, paths, nil
+}
+
+// collectContentFiles returns the plugin content files to archive with their disk paths keyed by the name in the archive, filtered by the include, exclude and .pluginignore dirs
+func collectContentFiles(pluginDir string, pluginContentTempDir string) ([]archiver.File, map[string]string, error) {
+	sources, err := contentSources(pluginContentTempDir, contentDirs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get content dirs: %w", err)
+	}
+
+	files, paths, err := archiveSources(sources, symlinkMode, mergeConflict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ignoredDirs, err := readPluginIgnore(pluginDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read content dirs to ignore: %w", err)
+	}
+	files = filterContentDirs(files, includeDirs, append(ignoredDirs, excludeDirs...))
+	logger.Infof("including content: %s", strings.Join(contentTopDirs(files), ", "))
+
+	return files, paths, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const storageProviderS3 = "s3"
+const storageProviderGCS = "gcs"
+
+// StorageBackend uploads the content to the url returned by the API
+type StorageBackend interface {
+	Upload(ctx context.Context, url string, reader io.Reader, size int64, contentType string) error
+}
+
+// storageBackendFor returns the backend for the provider hint of the file metadata, presigned PUT is used if the API gives no hint.
+// The S3 uploads with the part urls are uploaded by parts.
+func storageBackendFor(metadata FileMetadata) (StorageBackend, error) {
+	switch metadata.Provider {
+	case "", storageProviderS3:
+		if len(metadata.Parts) > 0 {
+			return multipartStorage{parts: metadata.Parts, partSize: metadata.PartSize, concurrency: partConcurrency, headUrl: metadata.HeadUrl}, nil
+		}
+		return presignedPutStorage{headUrl: metadata.HeadUrl}, nil
+	case storageProviderGCS:
+		return gcsResumableStorage{headUrl: metadata.HeadUrl}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage provider '%s'", metadata.Provider)
+	}
+}
+
+// presignedPutStorage uploads the content with a single PUT to an S3 style presigned url
+type presignedPutStorage struct {
+	headUrl string // presigned url verifying the uploaded object size if the ETag is not the content MD5
+}
+
+func (s presignedPutStorage) Upload(ctx context.Context, url string, reader io.Reader, size int64, contentType string) error {
+	// Calculate the MD5 of the sent content to compare it with the ETag returned by S3
+	hasher := md5.New()
+	counter := &countingReader{reader: io.TeeReader(reader, hasher)}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, counter)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = size
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := sendStorageRequest(req, "failed to upload a file")
+	if err != nil {
+		return err
+	}
+
+	if counter.n != size {
+		return fmt.Errorf("sent %d bytes of %d", counter.n, size)
+	}
+
+	err = verifyUploadedObject(ctx, s.headUrl, resp.Header.Get("ETag"), hex.EncodeToString(hasher.Sum(nil)), size)
+	if err != nil {
+		return fmt.Errorf("failed to verify the uploaded file: %w", err)
+	}
+
+	return nil
+}
+
+// gcsResumableStorage uploads the content to a GCS signed url starting a resumable upload session
+type gcsResumableStorage struct {
+	headUrl string // signed url verifying the uploaded object size
+}
+
+func (s gcsResumableStorage) Upload(ctx context.Context, url string, reader io.Reader, size int64, contentType string) error {
+	// Start the resumable upload session, the session uri is returned in the Location header
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-goog-resumable", "start")
+
+	resp, err := sendStorageRequest(req, "failed to start a resumable upload")
+	if err != nil {
+		return err
+	}
+
+	sessionUrl := resp.Header.Get("Location")
+	if sessionUrl == "" {
+		return fmt.Errorf("failed to start a resumable upload, no session url")
+	}
+
+	counter := &countingReader{reader: reader}
+	req, err = http.NewRequestWithContext(ctx, "PUT", sessionUrl, counter)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = size
+
+	_, err = sendStorageRequest(req, "failed to upload a file")
+	if err != nil {
+		return err
+	}
+
+	if counter.n != size {
+		return fmt.Errorf("sent %d bytes of %d", counter.n, size)
+	}
+
+	err = verifyObjectSize(ctx, s.headUrl, size)
+	if err != nil {
+		return fmt.Errorf("failed to verify the uploaded file: %w", err)
+	}
+
+	return nil
+}
+
+// sendStorageRequest sends the request and drains the response, the error status codes are returned as api errors
+func sendStorageRequest(req *http.Request, operation string) (*http.Response, error) {
+	client := httpClient
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, newApiError(operation, resp.StatusCode, body)
+	}
+
+	return resp, nil
+}
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const outputText = "text"
+const outputJson = "json"
+
+type FileSummary struct {
+	Type       string  `json:"type"`
+	Path       string  `json:"path"`
+	Version    int     `json:"version,omitempty"`
+	Attempts   int     `json:"attempts"`
+	RetryDelay float64 `json:"retryDelay"` // total delay between attempts in seconds
+}
+
+type JobSummary struct {
+	Id       string `json:"id"`
+	Platform string `json:"platform,omitempty"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+}
+
+type PhaseSummary struct {
+	Name     string  `json:"name"`
+	Duration float64 `json:"duration"` // phase duration in seconds
+}
+
+type EntityFileSummary struct {
+	Id         string `json:"id"`
+	Type       string `json:"type"`
+	Platform   string `json:"platform,omitempty"`
+	Deployment string `json:"deployment,omitempty"`
+	Version    int    `json:"version"`
+	Size       int64  `json:"size"`
+}
+
+type CheckSummary struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type ReleaseSummary struct {
+	Id      string `json:"id"`
+	Version string `json:"version,omitempty"`
+	Deleted bool   `json:"deleted"`
+}
+
+type UpdateSummary struct {
+	Platform string `json:"platform"`
+	Current  string `json:"current"`
+	Latest   string `json:"latest"`
+	Update   bool   `json:"update"`
+	Reason   string `json:"reason"`
+}
+
+type PluginSummary struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Task    string `json:"task"`
+	Version string `json:"version,omitempty"`
+
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Public      *bool  `json:"public,omitempty"`
+
+	Files  []FileSummary  `json:"files,omitempty"`
+	Dedup  int64          `json:"dedupSaved,omitempty"` // bytes not uploaded as the api already had the files
+	Jobs   []JobSummary   `json:"jobs,omitempty"`
+	Phases []PhaseSummary `json:"phases,omitempty"`
+
+	EntityFiles  []EntityFileSummary `json:"entityFiles,omitempty"`
+	DeletedFiles []EntityFileSummary `json:"deletedFiles,omitempty"`
+
+	Removed []string `json:"removed,omitempty"`
+
+	AbortedUploads []string `json:"abortedUploads,omitempty"`
+
+	PrunedReleases []ReleaseSummary `json:"prunedReleases,omitempty"`
+
+	Updates []UpdateSummary `json:"updates,omitempty"`
+
+	Checks []CheckSummary `json:"checks,omitempty"`
+
+	Plugins []PluginSummary `json:"plugins,omitempty"`
+}
+
+var summary Summary
+
+// summaryOutput receives the printed summary, stderr when stdout is reserved for the downloaded file
+var summaryOutput io.Writer = os.Stdout
+
+// addFile records the upload of a file with its version and retry statistics
+func (s *Summary) addFile(fileType string, path string, version int, stats retryStats) {
+	if stats.Attempts > 1 {
+		logger.Warningf("file '%s' required %d attempts to upload", path, stats.Attempts)
+	}
+
+	s.Files = append(s.Files, FileSummary{
+		Type:       fileType,
+		Path:       path,
+		Version:    version,
+		Attempts:   stats.Attempts,
+		RetryDelay: stats.Delay.Seconds(),
+	})
+}
+
+// addJobs records the final status of the jobs
+func (s *Summary) addJobs(jobs []JobMetadata) {
+	for _, job := range jobs {
+		var id string
+		if job.Id != nil {
+			id = job.Id.String()
+		}
+
+		s.Jobs = append(s.Jobs, JobSummary{
+			Id:       id,
+			Platform: job.Platform,
+			Status:   job.Status,
+			Message:  job.Message,
+		})
+	}
+}
+
+// addPhase records the duration of a task phase, the durations of a repeated phase add up, e.g. the uploads of the package and its release notes
+func (s *Summary) addPhase(name string, duration time.Duration) {
+	for i := range s.Phases {
+		if s.Phases[i].Name == name {
+			s.Phases[i].Duration += duration.Seconds()
+			return
+		}
+	}
+	s.Phases = append(s.Phases, PhaseSummary{Name: name, Duration: duration.Seconds()})
+}
+
+// newEntityFileSummary returns the summary of the entity file metadata
+func newEntityFileSummary(file FileMetadata) EntityFileSummary {
+	f := EntityFileSummary{
+		Type:       file.Type,
+		Platform:   file.Platform,
+		Deployment: file.Deployment,
+		Version:    file.Version,
+	}
+	if file.Id != nil {
+		f.Id = file.Id.String()
+	}
+	if file.Size != nil {
+		f.Size = *file.Size
+	}
+	return f
+}
+
+// addEntityFiles records the existing entity files
+func (s *Summary) addEntityFiles(files []FileMetadata) {
+	for _, file := range files {
+		s.EntityFiles = append(s.EntityFiles, newEntityFileSummary(file))
+	}
+}
+
+// addDeletedFile records the deleted entity file
+func (s *Summary) addDeletedFile(file FileMetadata) {
+	s.DeletedFiles = append(s.DeletedFiles, newEntityFileSummary(file))
+}
+
+// addDedupSaved records the size of the file registered without uploading
+func (s *Summary) addDedupSaved(size int64) {
+	s.Dedup += size
+}
+
+// addRemoved records the removed local file or dir
+func (s *Summary) addRemoved(path string) {
+	s.Removed = append(s.Removed, path)
+}
+
+// addAbortedUpload records the aborted multipart upload
+func (s *Summary) addAbortedUpload(uploadId string) {
+	s.AbortedUploads = append(s.AbortedUploads, uploadId)
+}
+
+// addPrunedRelease records the release deleted by the retention policy or only selected for the deletion in the dry run
+func (s *Summary) addPrunedRelease(release ReleaseMetadata, deleted bool) {
+	s.PrunedReleases = append(s.PrunedReleases, ReleaseSummary{Id: release.Id.String(), Version: release.Version, Deleted: deleted})
+}
+
+// addPlugin records the result of the plugin tasks
+func (s *Summary) addPlugin(name string, err error) {
+	p := PluginSummary{Name: name, Status: "ok"}
+	if err != nil {
+		p.Status = "failed"
+		p.Error = err.Error()
+	}
+	s.Plugins = append(s.Plugins, p)
+}
+
+// summaryPrinted is set once the summary is printed, the run prints a single summary document
+var summaryPrinted bool
+
+// printFailureSummary prints the summary of the failed run from the exit handler, the failures of the plugin tasks are reported in the summary of the plugins run
+func printFailureSummary() {
+	if inPluginTasks {
+		return
+	}
+	printSummary()
+}
+
+// printSummary writes the summary to the summary output in the configured output format, only the first call prints it
+func printSummary() {
+	if summaryPrinted {
+		return
+	}
+	summaryPrinted = true
+
+	if output == outputJson {
+		b, err := json.Marshal(summary)
+		if err != nil {
+			logger.Errorf("failed to serialize summary: %v", err)
+			return
+		}
+		fmt.Fprintln(summaryOutput, string(b))
+		return
+	}
+
+	// The text summary is informational only
+	if quiet {
+		return
+	}
+
+	fmt.Fprintf(summaryOutput, "task: %s\n", summary.Task)
+	if summary.Version != "" {
+		fmt.Fprintf(summaryOutput, "version: %s\n", summary.Version)
+	}
+	if summary.Name != "" {
+		fmt.Fprintf(summaryOutput, "name: %s\n", summary.Name)
+	}
+	if summary.Description != "" {
+		fmt.Fprintf(summaryOutput, "description: %s\n", summary.Description)
+	}
+	if summary.Public != nil {
+		if *summary.Public {
+			fmt.Fprintf(summaryOutput, "visibility: public\n")
+		} else {
+			fmt.Fprintf(summaryOutput, "visibility: private\n")
+		}
+	}
+	for _, file := range summary.Files {
+		fmt.Fprintf(summaryOutput, "file: %s (%s), version: %d, attempts: %d, retry delay: %.1fs\n", file.Path, file.Type, file.Version, file.Attempts, file.RetryDelay)
+	}
+	if summary.Dedup > 0 {
+		fmt.Fprintf(summaryOutput, "dedup saved: %d bytes\n", summary.Dedup)
+	}
+	for _, job := range summary.Jobs {
+		fmt.Fprintf(summaryOutput, "job: %s (%s), status: %s\n", job.Id, job.Platform, job.Status)
+	}
+	for _, phase := range summary.Phases {
+		fmt.Fprintf(summaryOutput, "phase: %s, duration: %.1fs\n", phase.Name, phase.Duration)
+	}
+	if len(summary.EntityFiles) > 0 {
+		w := tabwriter.NewWriter(summaryOutput, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tPLATFORM\tDEPLOYMENT\tVERSION\tSIZE\tID")
+		for _, file := range summary.EntityFiles {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", file.Type, file.Platform, file.Deployment, file.Version, file.Size, file.Id)
+		}
+		_ = w.Flush()
+	}
+	for _, file := range summary.DeletedFiles {
+		fmt.Fprintf(summaryOutput, "deleted: %s (%s), version: %d\n", file.Id, file.Type, file.Version)
+	}
+	for _, path := range summary.Removed {
+		fmt.Fprintf(summaryOutput, "removed: %s\n", path)
+	}
+	for _, uploadId := range summary.AbortedUploads {
+		fmt.Fprintf(summaryOutput, "aborted upload: %s\n", uploadId)
+	}
+	for _, release := range summary.PrunedReleases {
+		if release.Deleted {
+			fmt.Fprintf(summaryOutput, "pruned release: %s, version: %s\n", release.Id, release.Version)
+		} else {
+			fmt.Fprintf(summaryOutput, "would prune release: %s, version: %s\n", release.Id, release.Version)
+		}
+	}
+	for _, update := range summary.Updates {
+		fmt.Fprintf(summaryOutput, "update: %s, current: %s, latest: %s, update: %t, reason: %s\n", update.Platform, update.Current, update.Latest, update.Update, update.Reason)
+	}
+	if len(summary.Plugins) > 0 {
+		w := tabwriter.NewWriter(summaryOutput, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PLUGIN\tSTATUS\tERROR")
+		for _, p := range summary.Plugins {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.Status, p.Error)
+		}
+		_ = w.Flush()
+	}
+	for _, check := range summary.Checks {
+		fmt.Fprintf(summaryOutput, "[%s] %s: %s\n", check.Status, check.Name, check.Detail)
+	}
+}
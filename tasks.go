@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// TaskInfo describes a supported task for the usage and -listTasks output
+type TaskInfo struct {
+	Name        string
+	Description string
+	Flags       []string // required flags, the api url (-api or -env) and the token (-token or the login task) are required by all the api tasks
+}
+
+// taskRegistry lists the supported tasks in the order they usually run in
+var taskRegistry = []TaskInfo{
+	{Name: taskLogin, Description: "log in with the device code flow and cache the token for the other tasks", Flags: []string{"-authUrl"}},
+	{Name: taskDoctor, Description: "check the environment, the api and the project setup"},
+	{Name: taskPackagePlugin, Description: "build and cook the plugin with the engine UAT", Flags: []string{"-plugin", "-platform"}},
+	{Name: taskManifest, Description: "write the manifest of the plugin content files without uploading them", Flags: []string{"-plugin"}},
+	{Name: taskUploadPackageSource, Description: "archive and upload the plugin descriptor and content, create the package jobs", Flags: []string{"-entityId", "-plugin"}},
+	{Name: taskUploadFile, Description: "attach a standalone file to the entity", Flags: []string{"-entityId", "-filePath", "-type"}},
+	{Name: taskUnzipPackageSource, Description: "extract the downloaded package archive into the plugin dir", Flags: []string{"-plugin"}},
+	{Name: taskVerifyRelease, Description: "compare the entity files with the upload manifest", Flags: []string{"-entityId", "-manifest"}},
+	{Name: taskListFiles, Description: "list the entity files", Flags: []string{"-entityId"}},
+	{Name: taskDownload, Description: "download the entity files", Flags: []string{"-entityId", "-destDir"}},
+	{Name: taskDeleteFile, Description: "delete the entity files by id or type", Flags: []string{"-entityId", "-fileId or -type"}},
+	{Name: taskCheckUpdate, Description: "compare the project version with the latest app release of every platform, the updates are not applied", Flags: []string{"-appId", "-platform"}},
+	{Name: taskPruneReleases, Description: "delete the old app releases, keeping the most recent ones", Flags: []string{"-appId"}},
+	{Name: taskAbortMultipart, Description: "abort the incomplete multipart uploads of the entity left by the interrupted runs", Flags: []string{"-entityId"}},
+	{Name: taskClean, Description: "remove the generated plugin archives and temp content", Flags: []string{"-plugin"}},
+}
+
+// taskNames returns the names of the supported tasks
+func taskNames() []string {
+	names := make([]string, 0, len(taskRegistry))
+	for _, task := range taskRegistry {
+		names = append(names, task.Name)
+	}
+	return names
+}
+
+// printTasks writes the supported tasks with their descriptions and required flags
+func printTasks(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tDESCRIPTION\tREQUIRED FLAGS")
+	for _, task := range taskRegistry {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", task.Name, task.Description, strings.Join(task.Flags, ", "))
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Pattern of the temporary directories holding the archives during the upload
+const tempArchiveDirPattern = "veverse-sdk-automation-*"
+
+// Temporary archive directories older than this are considered left over by crashed runs
+const staleTempArchiveAge = 24 * time.Hour
+
+// archiveParentDir returns the dir the temporary archive directories are created in, the OS temp dir if not set
+func archiveParentDir(archiveRootDir string) string {
+	if archiveRootDir == "" {
+		return os.TempDir()
+	}
+	return archiveRootDir
+}
+
+// cleanStaleTempArchives removes the temporary archive directories in the archive root dir left over by crashed runs
+func cleanStaleTempArchives(archiveRootDir string) {
+	dirs, err := filepath.Glob(filepath.Join(archiveParentDir(archiveRootDir), tempArchiveDirPattern))
+	if err != nil {
+		logger.Warningf("failed to look for stale temp archives: %v", err)
+	}
+
+	for _, dir := range dirs {
+		fi, err := os.Stat(dir)
+		if err != nil || !fi.IsDir() || time.Since(fi.ModTime()) < staleTempArchiveAge {
+			continue
+		}
+
+		logger.Infof("removing stale temp archive dir '%s'", dir)
+		if err = os.RemoveAll(dir); err != nil {
+			logger.Warningf("failed to remove stale temp archive dir: %v", err)
+		}
+	}
+}
+
+// removeTempArchive closes the archive file if still open and removes its temporary directory
+func removeTempArchive(file *os.File, dir string) {
+	if file != nil {
+		if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			logger.Errorf("failed to close a zip file: %v", err)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		logger.Errorf("failed to delete temp archive dir: %v", err)
+	}
+}
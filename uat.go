@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Launcher installed engines are associated by their version, source builds by a GUID
+var engineVersionAssociationRegexp = regexp.MustCompile(`^\d+\.\d+$`)
+
+type ProjectDescriptor struct {
+	FileVersion       int    `json:"FileVersion"`
+	EngineAssociation string `json:"EngineAssociation"`
+}
+
+// getProjectFile returns the path of the uproject file in the project dir
+func getProjectFile(projectName string) (string, error) {
+	projectDir, err := getProjectDir(projectName)
+	if err != nil {
+		return "", err
+	}
+
+	items, err := os.ReadDir(projectDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read project dir: %w", err)
+	}
+
+	for _, item := range items {
+		if projectName != "" {
+			if strings.ToLower(item.Name()) == strings.ToLower(projectName+".uproject") {
+				return filepath.Join(projectDir, item.Name()), nil
+			}
+		} else if strings.ToLower(filepath.Ext(item.Name())) == ".uproject" {
+			return filepath.Join(projectDir, item.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to find the uproject file in %s", projectDir)
+}
+
+// getProjectDescriptor parses the uproject file
+func getProjectDescriptor(projectName string) (ProjectDescriptor, error) {
+	var descriptor ProjectDescriptor
+
+	projectFile, err := getProjectFile(projectName)
+	if err != nil {
+		return descriptor, err
+	}
+
+	b, err := os.ReadFile(projectFile)
+	if err != nil {
+		return descriptor, fmt.Errorf("failed to read project descriptor: %w", err)
+	}
+
+	err = json.Unmarshal(b, &descriptor)
+	if err != nil {
+		return descriptor, fmt.Errorf("failed to parse project descriptor: %w", err)
+	}
+
+	return descriptor, nil
+}
+
+// findUATPath looks for the RunUAT script of the launcher installed engine the project is associated with
+func findUATPath(projectName string) (string, error) {
+	descriptor, err := getProjectDescriptor(projectName)
+	if err != nil {
+		return "", err
+	}
+
+	if !engineVersionAssociationRegexp.MatchString(descriptor.EngineAssociation) {
+		return "", fmt.Errorf("unable to locate the engine '%s' the project is associated with, pass -uatPath", descriptor.EngineAssociation)
+	}
+
+	var engineDir string
+	script := "RunUAT.sh"
+	switch runtime.GOOS {
+	case "windows":
+		engineDir = filepath.Join(os.Getenv("ProgramFiles"), "Epic Games", "UE_"+descriptor.EngineAssociation)
+		script = "RunUAT.bat"
+	case "darwin":
+		engineDir = filepath.Join("/Users/Shared/Epic Games", "UE_"+descriptor.EngineAssociation)
+	default:
+		return "", fmt.Errorf("unable to locate the engine on %s, pass -uatPath", runtime.GOOS)
+	}
+
+	uatPath := filepath.Join(engineDir, "Engine", "Build", "BatchFiles", script)
+	if _, err = os.Stat(uatPath); err != nil {
+		return "", fmt.Errorf("failed to find %s, pass -uatPath: %w", uatPath, err)
+	}
+
+	return uatPath, nil
+}
+
+// packagePlugin runs the UAT BuildPlugin command packaging the plugin into the package dir, the UAT output is logged line by line
+func packagePlugin(uatPath string, pluginFile string, packageDir string, targetPlatform string) error {
+	args := []string{"BuildPlugin", fmt.Sprintf("-Plugin=%s", pluginFile), fmt.Sprintf("-Package=%s", packageDir), "-Rocket"}
+	if targetPlatform != "" {
+		args = append(args, fmt.Sprintf("-TargetPlatforms=%s", targetPlatform))
+	}
+
+	logger.Infof("running %s %s", uatPath, strings.Join(args, " "))
+
+	cmd := exec.Command(uatPath, args...)
+
+	// Stream both stdout and stderr through the logger
+	pipeReader, pipeWriter := io.Pipe()
+	cmd.Stdout = pipeWriter
+	cmd.Stderr = pipeWriter
+
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		scanner := bufio.NewScanner(pipeReader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			logger.WithField("uat", true).Info(scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			logger.Errorf("failed to read UAT output: %v", err)
+		}
+	}()
+
+	err := cmd.Run()
+	_ = pipeWriter.Close()
+	<-logged
+
+	if err != nil {
+		return fmt.Errorf("UAT failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"github.com/klauspost/compress/zip"
+	"github.com/mholt/archiver/v4"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultExtractDir = "Content"
+
+// File marking the extract dir created by the unzipPackageSource task, the clean task only removes the marked dirs
+const extractMarkerFileName = ".veverse-extracted"
+
+// crc32File calculates the IEEE CRC-32 checksum of the file content as used by zip archives
+func crc32File(path string) (uint32, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	h := crc32.NewIEEE()
+	if _, err = io.Copy(h, file); err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return h.Sum32(), nil
+}
+
+// isExtracted reports whether the archive entry has already been extracted to the destination, comparing the size and the checksum if the archive provides one
+func isExtracted(f archiver.File, dest string) bool {
+	fi, err := os.Stat(dest)
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() != f.Size() {
+		return false
+	}
+
+	hdr, ok := f.Header.(zip.FileHeader)
+	if !ok {
+		return true
+	}
+
+	crc, err := crc32File(dest)
+	if err != nil {
+		return false
+	}
+
+	return crc == hdr.CRC32
+}
+
+// isWithinDir reports whether the path is the dir or is under the dir
+func isWithinDir(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveExtractDir returns the extraction root, a relative dir is resolved against the plugin dir, the root must be within the plugin dir unless allowed otherwise
+func resolveExtractDir(pluginDir string, extractDir string, allowOutside bool) (string, error) {
+	root := extractDir
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(pluginDir, root)
+	}
+	root = filepath.Clean(root)
+
+	if !allowOutside && !isWithinDir(pluginDir, root) {
+		return "", fmt.Errorf("extract dir %s is outside of the plugin dir %s, pass -allowOutsidePlugin to allow", root, pluginDir)
+	}
+
+	return root, nil
+}
+
+// prepareExtractDir creates the extract dir and marks it as the extraction output if it does not exist yet.
+// The existing dirs, e.g. the plugin Content dir holding the source content, are extracted to without the marker.
+func prepareExtractDir(root string) error {
+	_, err := os.Stat(root)
+	if err == nil {
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat %s: %w", root, err)
+	}
+
+	err = os.MkdirAll(root, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", root, err)
+	}
+
+	err = os.WriteFile(filepath.Join(root, extractMarkerFileName), []byte(time.Now().Format(time.RFC3339)), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to mark %s as the extract dir: %w", root, err)
+	}
+
+	return nil
+}
+
+// isMarkedExtractDir reports whether the dir was created by the extraction
+func isMarkedExtractDir(root string) bool {
+	fi, err := os.Stat(filepath.Join(root, extractMarkerFileName))
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// extractPath returns the destination of the archive entry under the root, failing if the entry escapes the root.
+// Backslashes in the names of the archives created on Windows are treated as separators.
+func extractPath(root string, name string) (string, error) {
+	path := filepath.Join(root, filepath.FromSlash(archiveEntryName(name)))
+	if !isWithinDir(root, path) {
+		return "", fmt.Errorf("illegal archive entry path '%s' outside of %s", name, root)
+	}
+	return path, nil
+}
+
+// matchesIncludePatterns reports whether the archive entry or any of its parent dirs matches any of the glob patterns, all entries match if there are no patterns
+func matchesIncludePatterns(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	name = strings.TrimSuffix(archiveEntryName(name), "/")
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(archiveEntryName(pattern), "/")
+		for p := name; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// extractedEntry is an archive entry expected at the destination after the extraction
+type extractedEntry struct {
+	name  string
+	dest  string
+	size  int64
+	isDir bool
+}
+
+// findMissingEntries returns the entries missing at the destination or written partially, e.g. the files the extraction failed to write
+func findMissingEntries(entries []extractedEntry) []extractedEntry {
+	var missing []extractedEntry
+	for _, entry := range entries {
+		fi, err := os.Stat(entry.dest)
+		if err != nil {
+			missing = append(missing, entry)
+			continue
+		}
+
+		if entry.isDir {
+			if !fi.IsDir() {
+				missing = append(missing, entry)
+			}
+		} else if !fi.Mode().IsRegular() || fi.Size() != entry.size {
+			missing = append(missing, entry)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFindMissingEntriesReadOnlyDir(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		t.Skip("the read-only dirs are writable by root and on windows")
+	}
+
+	root := t.TempDir()
+	readOnly := filepath.Join(root, "Maps")
+	if err := os.Mkdir(readOnly, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []extractedEntry{
+		{name: "Maps/", dest: readOnly, isDir: true},
+		{name: "Maps/Level.umap", dest: filepath.Join(readOnly, "Level.umap"), size: 5},
+		{name: "Textures/", dest: filepath.Join(root, "Textures"), isDir: true},
+		{name: "Textures/A.uasset", dest: filepath.Join(root, "Textures", "A.uasset"), size: 7},
+	}
+
+	if err := os.Chmod(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(readOnly, 0755) })
+
+	// Extract the entries ignoring the errors, the file in the read-only dir fails to be written
+	if err := os.MkdirAll(filepath.Join(root, "Textures"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "Textures", "A.uasset"), []byte("texture"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(readOnly, "Level.umap"), []byte("level"), 0644); err == nil {
+		t.Fatal("wrote a file to the read-only dir")
+	}
+
+	missing := findMissingEntries(entries)
+	if len(missing) != 1 || missing[0].name != "Maps/Level.umap" {
+		t.Errorf("missing entries %v, want the file in the read-only dir only", missing)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"strings"
+)
+
+// shouldUpdate reports whether the current version should be updated to the latest one and why, the latest version must satisfy the constraint if any
+func shouldUpdate(current *semver.Version, latest *semver.Version, constraint *semver.Constraints) (bool, string) {
+	if !current.LessThan(latest) {
+		return false, fmt.Sprintf("current version %s is up to date with the latest %s", current.String(), latest.String())
+	}
+
+	if constraint != nil {
+		if ok, errs := constraint.Validate(latest); !ok {
+			var reasons []string
+			for _, err := range errs {
+				reasons = append(reasons, err.Error())
+			}
+			return false, fmt.Sprintf("latest version %s does not satisfy the update constraint %s: %s", latest.String(), constraint.String(), strings.Join(reasons, ", "))
+		}
+	}
+
+	return true, fmt.Sprintf("updating from %s to %s", current.String(), latest.String())
+}
+
+// checkUpdates compares the current version with the latest release of every platform, returns the update decision of each platform
+func checkUpdates(current *semver.Version, platforms []string, constraint *semver.Constraints) ([]UpdateSummary, error) {
+	latestVersions, err := getLatestVersions(platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []UpdateSummary
+	for _, p := range platforms {
+		latest, ok := latestVersions[p]
+		if !ok {
+			continue
+		}
+		delete(latestVersions, p)
+
+		update, reason := shouldUpdate(current, latest, constraint)
+		logger.WithField("platform", p).Infof("%s", reason)
+		updates = append(updates, UpdateSummary{
+			Platform: p,
+			Current:  current.String(),
+			Latest:   latest.String(),
+			Update:   update,
+			Reason:   reason,
+		})
+	}
+
+	return updates, nil
+}
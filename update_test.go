@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"github.com/gofrs/uuid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckUpdates(t *testing.T) {
+	latest := map[string]string{"Win64": "1.2.3", "Linux": "1.3.0"}
+	appId = uuid.Must(uuid.NewV4())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/apps/%s/releases/latest", appId.String()) {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"version":%q}}`, latest[r.URL.Query().Get("platform")])
+	}))
+	defer server.Close()
+	apiUrl = server.URL
+	httpClient = &http.Client{}
+
+	constraint, err := semver.NewConstraint("~1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := checkUpdates(semver.MustParse("1.2.0"), []string{"Win64", "Linux", "Win64"}, constraint)
+	if err != nil {
+		t.Fatalf("failed to check the updates: %v", err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("expected an update decision per platform, got %+v", updates)
+	}
+	if updates[0].Platform != "Win64" || !updates[0].Update || updates[0].Latest != "1.2.3" {
+		t.Errorf("expected the Win64 update to 1.2.3, got %+v", updates[0])
+	}
+	if updates[1].Platform != "Linux" || updates[1].Update {
+		t.Errorf("expected no Linux update outside of the constraint, got %+v", updates[1])
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"github.com/gofrs/uuid"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// uploadStandaloneFile uploads an arbitrary file, e.g. an icon or a changelog, to the entity with the presigned url,
+// the mime is mapped by the extension or detected from the content and the original path defaults to the file name if not set,
+// the file is tagged with the release version if known
+func uploadStandaloneFile(entityId uuid.UUID, path string, fileType string, mime string, originalPath string, releaseVersion *semver.Version) (FileMetadata, retryStats, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return FileMetadata{}, retryStats{}, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if fi.IsDir() {
+		return FileMetadata{}, retryStats{}, fmt.Errorf("%s is a directory", path)
+	}
+
+	err = checkMaxSize("file", fi.Size())
+	if err != nil {
+		return FileMetadata{}, retryStats{}, err
+	}
+
+	if mime == "" {
+		mime, _ = overrideContentType(path)
+	}
+	if mime == "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return FileMetadata{}, retryStats{}, fmt.Errorf("failed to open file: %w", err)
+		}
+		mime = detectContentType(file, path, fi.Size(), defaultContentType)
+		_ = file.Close()
+	}
+	if originalPath == "" {
+		originalPath = filepath.Base(path)
+	}
+
+	existingFiles, err := getEntityFiles(entityId)
+	if err != nil {
+		return FileMetadata{}, retryStats{}, err
+	}
+
+	version, err := nextFileVersion(existingFiles, fileType, platform, deployment, force)
+	if err != nil {
+		return FileMetadata{}, retryStats{}, err
+	}
+
+	params := uploadUrlParams(releaseVersion)
+	if version > 0 {
+		params["version"] = strconv.Itoa(version)
+	}
+
+	metadata, stats, err := uploadPresignedFile(entityId, fileType, mime, path, originalPath, fi.Size(), params)
+	if err != nil {
+		return FileMetadata{}, stats, err
+	}
+	if metadata == nil {
+		return FileMetadata{Type: fileType, OriginalPath: originalPath, Version: version}, stats, nil
+	}
+
+	return *metadata, stats, nil
+}
+
+// uploadPresignedFile uploads the file to the presigned url, refreshing the url if it was rejected or is about to expire.
+// The content the api already has is registered without uploading it if -dedup is set, the file is sent directly to the api
+// if the presigned upload is unavailable and -allowMultipartFallback is set, no metadata is returned then.
+func uploadPresignedFile(entityId uuid.UUID, fileType string, contentType string, path string, originalPath string, size int64, params map[string]string) (*FileMetadata, retryStats, error) {
+	if duplicate := findDuplicateFile(entityId, fileType, contentType, path, originalPath, params); duplicate != nil {
+		summary.addDedupSaved(size)
+		return duplicate, retryStats{}, nil
+	}
+
+	endPresignPhase := startPhase(phasePresign)
+	metadata, err := getEntityFileUploadUrl(entityId, fileType, contentType, size, originalPath, params)
+	endPresignPhase()
+
+	endUploadPhase := startPhase(phaseUpload)
+	defer endUploadPhase()
+
+	if err != nil {
+		if !allowMultipartFallback || !isEndpointUnavailable(err) {
+			return nil, retryStats{}, fmt.Errorf("failed to get presigned upload file metadata: %w", err)
+		}
+
+		// Older APIs have no presigned upload, send the file directly to the API instead with the same query params
+		withErrorFields(err).Warningf("presigned upload is unavailable, falling back to the multipart upload: %v", err)
+		stats, err := withRetry(fileType+" multipart upload", func() error {
+			return uploadEntityFile(entityId, fileType, contentType, path, originalPath, params, formParams, defaultFileFieldName)
+		})
+		return nil, stats, err
+	}
+	logger.Debugf("uploading file %s", metadata.Id.String())
+
+	var refreshUrl bool
+	stats, err := withRetry(fileType+" upload", func() error {
+		// Request a fresh url if the previous one was rejected or is about to expire
+		if refreshUrl || presignedUrlExpiresSoon(metadata.Url) {
+			logger.Infof("refreshing the presigned upload url")
+			refreshed, err := getEntityFileUploadUrl(entityId, fileType, contentType, size, originalPath, params)
+			if err != nil {
+				return err
+			}
+			metadata = refreshed
+		}
+
+		err := uploadEntityFileToStorage(metadata, entityId, path, contentType)
+		refreshUrl = isPresignedUrlRejected(err)
+		return err
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+
+	return &metadata, stats, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+)
+
+// findManifestFile looks for the server file matching the manifest entry by id, or by type and original path if the id is unknown
+func findManifestFile(files []FileMetadata, expected ManifestFile) int {
+	for i, file := range files {
+		if expected.Id != nil && file.Id != nil {
+			if *expected.Id == *file.Id {
+				return i
+			}
+			continue
+		}
+
+		if file.Type == expected.Type && file.OriginalPath == expected.OriginalPath {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// verifyRelease checks that the entity files on the server exactly match the manifest
+func verifyRelease(entityId uuid.UUID, manifestPath string) error {
+	m, err := readManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	if !m.EntityId.IsNil() && m.EntityId != entityId {
+		return fmt.Errorf("manifest entity id %s does not match the entity id %s", m.EntityId.String(), entityId.String())
+	}
+
+	files, err := getEntityFiles(entityId)
+	if err != nil {
+		return err
+	}
+
+	var missing, mismatched int
+	matched := make([]bool, len(files))
+	for _, expected := range m.Files {
+		i := findManifestFile(files, expected)
+		if i < 0 {
+			logger.Errorf("missing file '%s' (%s)", expected.OriginalPath, expected.Type)
+			missing++
+			continue
+		}
+		matched[i] = true
+
+		file := files[i]
+		if file.Size != nil && *file.Size != expected.Size {
+			logger.Errorf("file '%s' (%s) size mismatch, expected: %d, actual: %d", expected.OriginalPath, expected.Type, expected.Size, *file.Size)
+			mismatched++
+		} else if file.Hash != nil && *file.Hash != expected.Hash {
+			logger.Errorf("file '%s' (%s) hash mismatch, expected: %s, actual: %s", expected.OriginalPath, expected.Type, expected.Hash, *file.Hash)
+			mismatched++
+		}
+	}
+
+	var extra int
+	for i, file := range files {
+		if !matched[i] {
+			logger.Errorf("unexpected file '%s' (%s)", file.OriginalPath, file.Type)
+			extra++
+		}
+	}
+
+	if missing > 0 || mismatched > 0 || extra > 0 {
+		return fmt.Errorf("release does not match the manifest, missing: %d, mismatched: %d, extra: %d", missing, mismatched, extra)
+	}
+
+	logger.Infof("release matches the manifest, %d files verified", len(m.Files))
+	return nil
+}
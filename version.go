@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Build information stamped by the release build with -ldflags "-X main.version=... -X main.buildDate=..."
+var (
+	version   = "dev"
+	buildDate = "unknown"
+)
+
+// printVersion writes the tool build information to stdout
+func printVersion() {
+	fmt.Printf("version: %s\ngo: %s\nbuild date: %s\n", version, runtime.Version(), buildDate)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// iniVersionLocation is a key of a project config ini file holding the project version
+type iniVersionLocation struct {
+	Section string
+	Key     string
+	File    string // file name in the project Config dir
+}
+
+func (l iniVersionLocation) String() string {
+	return fmt.Sprintf("%s [%s] %s", l.File, l.Section, l.Key)
+}
+
+// defaultVersionLocation is the ProjectVersion of the project settings always checked first
+var defaultVersionLocation = iniVersionLocation{Section: "/Script/EngineSettings.GeneralProjectSettings", Key: "ProjectVersion", File: "DefaultGame.ini"}
+
+// projectVersionLocations are the locations checked in order for the project version, the -versionKey ones follow the default
+var projectVersionLocations = []iniVersionLocation{defaultVersionLocation}
+
+// parseVersionLocation parses the section:key:file location, the section may contain colons and the file defaults to DefaultGame.ini if empty
+func parseVersionLocation(value string) (iniVersionLocation, error) {
+	i := strings.LastIndex(value, ":")
+	if i < 0 {
+		return iniVersionLocation{}, fmt.Errorf("invalid version key '%s', expected section:key:file", value)
+	}
+	file := strings.TrimSpace(value[i+1:])
+	rest := value[:i]
+
+	i = strings.LastIndex(rest, ":")
+	if i < 0 {
+		return iniVersionLocation{}, fmt.Errorf("invalid version key '%s', expected section:key:file", value)
+	}
+	location := iniVersionLocation{
+		Section: strings.TrimSpace(rest[:i]),
+		Key:     strings.TrimSpace(rest[i+1:]),
+		File:    file,
+	}
+
+	if location.Section == "" || location.Key == "" {
+		return iniVersionLocation{}, fmt.Errorf("invalid version key '%s', no section or key", value)
+	}
+	if location.File == "" {
+		location.File = defaultVersionLocation.File
+	}
+	if strings.ContainsAny(location.File, `/\`) {
+		return iniVersionLocation{}, fmt.Errorf("invalid version key '%s', the file must be in the project Config dir", value)
+	}
+
+	return location, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+)
+
+// nextFileVersion returns the version to upload a file of the type with, 0 if there is no such file yet.
+// Existing files are only superseded by a new version when forced.
+func nextFileVersion(files []FileMetadata, fileType string, platform string, deployment string, force bool) (int, error) {
+	exists := false
+	latest := 0
+	for _, file := range files {
+		if file.Type != fileType || file.Platform != platform || file.Deployment != deployment {
+			continue
+		}
+
+		exists = true
+		if file.Version > latest {
+			latest = file.Version
+		}
+	}
+
+	if !exists {
+		return 0, nil
+	}
+
+	if !force {
+		return 0, fmt.Errorf("a file of type '%s' already exists (version %d), pass -force to upload a new version", fileType, latest)
+	}
+
+	return latest + 1, nil
+}
+
+// hasFileOfType reports whether there is a file of the type for any platform and deployment
+func hasFileOfType(files []FileMetadata, fileType string) bool {
+	for _, file := range files {
+		if file.Type == fileType {
+			return true
+		}
+	}
+	return false
+}